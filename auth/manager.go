@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/common/dispatcher"
+)
+
+// Manager 监听raft中的 NamespaceAuth 数据，每个节点使用相同的策略
+type Manager struct {
+	locker sync.RWMutex
+	data   map[string][]byte
+	policy *Policy
+}
+
+func NewManager(center dispatcher.IDispatchCenter) *Manager {
+	m := &Manager{
+		data:   make(map[string][]byte),
+		policy: NewPolicy(nil),
+	}
+	center.Register(m.doEvent)
+	return m
+}
+
+func (m *Manager) doEvent(e dispatcher.IEvent) error {
+	switch e.Event() {
+	case eosc.EventInit, eosc.EventReset:
+		data := make(map[string][]byte)
+		for k, v := range e.All()[eosc.NamespaceAuth] {
+			data[k] = v
+		}
+		m.reset(data)
+	case eosc.EventSet, eosc.EventDel:
+		if e.Namespace() != eosc.NamespaceAuth {
+			return nil
+		}
+		m.locker.RLock()
+		data := make(map[string][]byte, len(m.data)+1)
+		for k, v := range m.data {
+			data[k] = v
+		}
+		m.locker.RUnlock()
+		if e.Event() == eosc.EventSet {
+			data[e.Key()] = e.Data()
+		} else {
+			delete(data, e.Key())
+		}
+		m.reset(data)
+	}
+	return nil
+}
+
+func (m *Manager) reset(data map[string][]byte) {
+	policy := NewPolicy(data)
+	m.locker.Lock()
+	m.data = data
+	m.policy = policy
+	m.locker.Unlock()
+}
+
+func (m *Manager) current() *Policy {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+	return m.policy
+}
+
+// Handler 认证及鉴权，通过后请求头中会带上签名后的用户信息
+func (m *Manager) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := m.current()
+		user, forwarded := policy.verifyForward(r)
+		r.Header.Del(HeaderPrincipal)
+		r.Header.Del(HeaderPrincipalSign)
+		if !policy.Enable() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !forwarded {
+			var err error
+			user, err = policy.Authenticate(r)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, err)
+				return
+			}
+		}
+		if !user.AllowTenant(Tenant(r)) || !policy.Authorize(user, r.Method, Resource(r.URL.Path)) {
+			writeError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+			return
+		}
+		if err := policy.signForward(r, user); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		next.ServeHTTP(w, WithUser(r, user))
+	})
+}
+
+type userKey struct{}
+
+// RequestUser 返回 Handler 认证通过的用户，未启用认证时返回false
+func RequestUser(r *http.Request) (*User, bool) {
+	u, ok := r.Context().Value(userKey{}).(*User)
+	return u, ok
+}
+
+// WithUser 返回带有用户的请求，用于内部发起的请求，例如定时变更
+func WithUser(r *http.Request, u *User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, u))
+}
+
+// User 根据用户名返回用户，未启用认证时返回false
+func (m *Manager) User(name string) (*User, bool) {
+	u, has := m.current().users[name]
+	return u, has
+}
+
+// Authorize 判断用户是否有权限对资源执行method，未启用认证时不限制
+func (m *Manager) Authorize(u *User, method, resource string) bool {
+	policy := m.current()
+	if !policy.Enable() {
+		return true
+	}
+	return u != nil && policy.Authorize(u, method, resource)
+}
+
+func writeError(w http.ResponseWriter, code int, err interface{}) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	msg := ""
+	switch v := err.(type) {
+	case error:
+		msg = v.Error()
+	case string:
+		msg = v
+	}
+	data, _ := json.Marshal(map[string]interface{}{"code": code, "error": msg})
+	w.Write(data)
+}
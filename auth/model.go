@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/eolinker/eosc"
+)
+
+// NamespaceAuth 下的key前缀
+const (
+	KeyUserPrefix = "user:"
+	KeyRolePrefix = "role:"
+	// KeyForwardSecret 节点之间转发请求时用于签名用户身份的密钥
+	KeyForwardSecret = "cluster:secret"
+
+	All         = "*"
+	VerbRead    = "read"
+	VerbWrite   = "write"
+	MaskedValue = "******"
+)
+
+// User 可访问open api的用户，支持 bearer token、HMAC签名、客户端证书CN 三种认证方式
+type User struct {
+	Name string `json:"name" yaml:"name"`
+	// TokenHash bearer token 的sha256，不保存明文
+	TokenHash string `json:"token_hash,omitempty" yaml:"token_hash"`
+	// Secret HMAC签名的密钥
+	Secret string   `json:"secret,omitempty" yaml:"secret"`
+	CN     string   `json:"cn,omitempty" yaml:"cn"`
+	Roles  []string `json:"roles" yaml:"roles"`
+	// Tenants 允许访问的租户，为空时不限制，默认租户为 default
+	Tenants []string `json:"tenants,omitempty" yaml:"tenants"`
+}
+
+// AllowTenant 判断用户是否可以访问租户，tenant 为空时表示默认租户
+func (u *User) AllowTenant(tenant string) bool {
+	if len(u.Tenants) == 0 {
+		return true
+	}
+	if tenant == "" {
+		tenant = eosc.TenantDefault
+	}
+	for _, t := range u.Tenants {
+		if t == All || strings.EqualFold(t, tenant) {
+			return true
+		}
+	}
+	return false
+}
+
+// Masked 返回隐藏了敏感信息的用户数据
+func (u *User) Masked() *User {
+	m := *u
+	if m.TokenHash != "" {
+		m.TokenHash = MaskedValue
+	}
+	if m.Secret != "" {
+		m.Secret = MaskedValue
+	}
+	return &m
+}
+
+// Role 角色，由一组规则组成，满足任一规则即允许访问
+type Role struct {
+	Name  string  `json:"name" yaml:"name"`
+	Rules []*Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule resources 为profession名称或者 /api 之外的一级路径(例如 extender、variable)，methods 为HTTP方法或者 read/write
+type Rule struct {
+	Resources []string `json:"resources" yaml:"resources"`
+	Methods   []string `json:"methods" yaml:"methods"`
+}
+
+// Allow 判断用户的角色中是否有规则允许对资源执行method
+func Allow(roles map[string]*Role, u *User, method, resource string) bool {
+	for _, name := range u.Roles {
+		role, has := roles[name]
+		if !has {
+			continue
+		}
+		for _, rule := range role.Rules {
+			if rule.Match(method, resource) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Rule) Match(method, resource string) bool {
+	return matchResource(r.Resources, resource) && matchMethod(r.Methods, method)
+}
+
+func matchResource(resources []string, resource string) bool {
+	for _, v := range resources {
+		if v == All || strings.EqualFold(v, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMethod(methods []string, method string) bool {
+	method = strings.ToUpper(method)
+	for _, v := range methods {
+		switch strings.ToLower(v) {
+		case All:
+			return true
+		case VerbRead:
+			if isReadMethod(method) {
+				return true
+			}
+		case VerbWrite:
+			if !isReadMethod(method) {
+				return true
+			}
+		default:
+			if strings.EqualFold(v, method) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isReadMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// HashToken 计算bearer token保存时使用的hash
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func UserKey(name string) string {
+	return KeyUserPrefix + name
+}
+
+func RoleKey(name string) string {
+	return KeyRolePrefix + name
+}
+
+// Tenant 返回请求的租户，/t/{tenant}/ 前缀优先于 X-Tenant header，默认租户返回空
+func Tenant(r *http.Request) string {
+	tenant := r.Header.Get(HeaderTenant)
+	if vs := strings.Split(strings.Trim(r.URL.Path, "/"), "/"); len(vs) > 1 && vs[0] == "t" {
+		tenant = vs[1]
+	}
+	tenant = strings.ToLower(tenant)
+	if tenant == eosc.TenantDefault {
+		return ""
+	}
+	return tenant
+}
+
+// Resource 根据请求路径获取需要校验权限的资源名
+// /api/:profession/... 返回profession，其他路径返回第一级路径，/t/:tenant 前缀不影响资源名
+// transaction、batch、import、apply 会修改多个profession，由admin按每个操作的profession再次鉴权
+func Resource(path string) string {
+	vs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(vs) > 1 && vs[0] == "t" {
+		vs = vs[2:]
+		if len(vs) == 0 {
+			return ""
+		}
+	}
+	if len(vs) > 1 && vs[0] == "api" {
+		return strings.ToLower(vs[1])
+	}
+	return strings.ToLower(vs[0])
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eolinker/eosc/log"
+)
+
+const (
+	HeaderDate          = "X-Eosc-Date"
+	HeaderPrincipal     = "X-Eosc-Principal"
+	HeaderPrincipalSign = "X-Eosc-Principal-Sign"
+	HeaderTenant        = "X-Tenant"
+
+	SchemeBearer = "Bearer"
+	SchemeHMAC   = "HMAC-SHA256"
+
+	// signExpire 签名的有效时间
+	signExpire = time.Minute * 5
+)
+
+var (
+	ErrorUnauthorized = errors.New("unauthorized")
+	ErrorSignExpire   = errors.New("signature expired")
+	ErrorSignInvalid  = errors.New("invalid signature")
+)
+
+// Policy 认证及鉴权数据，由 NamespaceAuth 下的数据生成，生成后只读
+type Policy struct {
+	users  map[string]*User
+	roles  map[string]*Role
+	tokens map[string]*User
+	cns    map[string]*User
+	secret []byte
+}
+
+func NewPolicy(data map[string][]byte) *Policy {
+	p := &Policy{
+		users:  make(map[string]*User),
+		roles:  make(map[string]*Role),
+		tokens: make(map[string]*User),
+		cns:    make(map[string]*User),
+	}
+	for key, value := range data {
+		switch {
+		case key == KeyForwardSecret:
+			p.secret = value
+		case strings.HasPrefix(key, KeyUserPrefix):
+			u := new(User)
+			if err := json.Unmarshal(value, u); err != nil {
+				log.Warn("auth read user:", key, ":", err)
+				continue
+			}
+			p.users[u.Name] = u
+			if u.TokenHash != "" {
+				p.tokens[u.TokenHash] = u
+			}
+			if u.CN != "" {
+				p.cns[u.CN] = u
+			}
+		case strings.HasPrefix(key, KeyRolePrefix):
+			r := new(Role)
+			if err := json.Unmarshal(value, r); err != nil {
+				log.Warn("auth read role:", key, ":", err)
+				continue
+			}
+			p.roles[r.Name] = r
+		}
+	}
+	return p
+}
+
+// Enable 未配置任何用户时不开启认证，方便初始化
+func (p *Policy) Enable() bool {
+	return len(p.users) > 0
+}
+
+// Authenticate 依次尝试bearer token、HMAC签名以及客户端证书
+func (p *Policy) Authenticate(r *http.Request) (*User, error) {
+	authorization := r.Header.Get("Authorization")
+	if authorization != "" {
+		i := strings.Index(authorization, " ")
+		if i < 0 {
+			return nil, ErrorUnauthorized
+		}
+		scheme, value := authorization[:i], strings.TrimSpace(authorization[i+1:])
+		switch {
+		case strings.EqualFold(scheme, SchemeBearer):
+			if u, has := p.tokens[HashToken(value)]; has {
+				return u, nil
+			}
+			return nil, ErrorUnauthorized
+		case strings.EqualFold(scheme, SchemeHMAC):
+			return p.verifyHMAC(r, value)
+		}
+		return nil, ErrorUnauthorized
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.PeerCertificates) > 0 {
+		if u, has := p.cns[r.TLS.PeerCertificates[0].Subject.CommonName]; has {
+			return u, nil
+		}
+	}
+	return nil, ErrorUnauthorized
+}
+
+// verifyHMAC Authorization: HMAC-SHA256 Credential=<user>, Signature=<hex>
+// 签名内容为 method\nrequestURI\nX-Eosc-Date\nsha256(body)
+func (p *Policy) verifyHMAC(r *http.Request, value string) (*User, error) {
+	var credential, signature string
+	for _, kv := range strings.Split(value, ",") {
+		kv = strings.TrimSpace(kv)
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+		switch strings.ToLower(kv[:i]) {
+		case "credential":
+			credential = kv[i+1:]
+		case "signature":
+			signature = kv[i+1:]
+		}
+	}
+	u, has := p.users[credential]
+	if !has || u.Secret == "" {
+		return nil, ErrorUnauthorized
+	}
+	date := r.Header.Get(HeaderDate)
+	if err := checkDate(date); err != nil {
+		return nil, err
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignRequest(u.Secret, r.Method, r.RequestURI, date, body))) {
+		return nil, ErrorSignInvalid
+	}
+	return u, nil
+}
+
+// SignRequest 计算HMAC签名
+func SignRequest(secret, method, requestURI, date string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return sign([]byte(secret), strings.ToUpper(method), requestURI, date, hex.EncodeToString(bodyHash[:]))
+}
+
+func sign(secret []byte, values ...string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checkDate(date string) error {
+	t, err := strconv.ParseInt(date, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s:%w", HeaderDate, ErrorSignInvalid)
+	}
+	d := time.Since(time.Unix(t, 0))
+	if d > signExpire || d < -signExpire {
+		return ErrorSignExpire
+	}
+	return nil
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// Authorize 判断用户是否有权限对资源执行method
+func (p *Policy) Authorize(u *User, method, resource string) bool {
+	return Allow(p.roles, u, method, resource)
+}
+
+// signForward 为已认证的请求加上用户信息，admin据此对批量接口中的每个操作鉴权
+// 配置了secret时同时加上签名，转发到leader后不需要重新认证
+func (p *Policy) signForward(r *http.Request, u *User) error {
+	r.Header.Set(HeaderPrincipal, u.Name)
+	if len(p.secret) == 0 {
+		return nil
+	}
+	date := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := p.forwardSign(r, u.Name, date)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(HeaderDate, date)
+	r.Header.Set(HeaderPrincipalSign, signature)
+	return nil
+}
+
+// forwardSign 转发签名包含用户、method、uri、时间及body摘要
+func (p *Policy) forwardSign(r *http.Request, name, date string) (string, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return "", err
+	}
+	bodyHash := sha256.Sum256(body)
+	return sign(p.secret, name, strings.ToUpper(r.Method), r.RequestURI, date, hex.EncodeToString(bodyHash[:])), nil
+}
+
+// verifyForward 校验其他节点转发过来的用户信息
+func (p *Policy) verifyForward(r *http.Request) (*User, bool) {
+	name := r.Header.Get(HeaderPrincipal)
+	signature := r.Header.Get(HeaderPrincipalSign)
+	if name == "" || signature == "" || len(p.secret) == 0 {
+		return nil, false
+	}
+	date := r.Header.Get(HeaderDate)
+	if checkDate(date) != nil {
+		return nil, false
+	}
+	expect, err := p.forwardSign(r, name, date)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(expect)) {
+		return nil, false
+	}
+	u, has := p.users[name]
+	return u, has
+}
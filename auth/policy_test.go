@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testPolicy() *Policy {
+	data := map[string][]byte{KeyForwardSecret: []byte("cluster")}
+	users := []*User{
+		{Name: "viewer", TokenHash: HashToken("viewer-token"), Roles: []string{"read-setting"}},
+		{Name: "operator", Secret: "operator-secret", Roles: []string{"read-setting", "write-router"}},
+	}
+	roles := []*Role{
+		{Name: "read-setting", Rules: []*Rule{{Resources: []string{"setting"}, Methods: []string{VerbRead}}}},
+		{Name: "write-router", Rules: []*Rule{{Resources: []string{"router"}, Methods: []string{"*"}}}},
+	}
+	for _, u := range users {
+		data[UserKey(u.Name)], _ = json.Marshal(u)
+	}
+	for _, r := range roles {
+		data[RoleKey(r.Name)], _ = json.Marshal(r)
+	}
+	return NewPolicy(data)
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	p := testPolicy()
+	tests := []struct {
+		name   string
+		user   string
+		method string
+		path   string
+		want   bool
+	}{
+		{name: "read setting", user: "viewer", method: "GET", path: "/api/setting/log", want: true},
+		{name: "write setting", user: "viewer", method: "PUT", path: "/api/setting/log", want: false},
+		{name: "viewer write router", user: "viewer", method: "DELETE", path: "/api/router/demo", want: false},
+		{name: "operator write router", user: "operator", method: "DELETE", path: "/api/router/demo", want: true},
+		{name: "operator extender", user: "operator", method: "GET", path: "/extender", want: false},
+		{name: "operator write tenant router", user: "operator", method: "DELETE", path: "/t/team-a/api/router/demo", want: true},
+		{name: "viewer write tenant router", user: "viewer", method: "DELETE", path: "/t/team-a/api/router/demo", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Authorize(p.users[tt.user], tt.method, Resource(tt.path)); got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyAuthenticate(t *testing.T) {
+	p := testPolicy()
+
+	r := httptest.NewRequest("GET", "/api/setting", nil)
+	r.Header.Set("Authorization", "Bearer viewer-token")
+	if u, err := p.Authenticate(r); err != nil || u.Name != "viewer" {
+		t.Errorf("bearer: got %v, %v", u, err)
+	}
+
+	r = httptest.NewRequest("GET", "/api/setting", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	if _, err := p.Authenticate(r); err == nil {
+		t.Error("bearer: want error for wrong token")
+	}
+
+	body := []byte(`{"name":"demo"}`)
+	date := strconv.FormatInt(time.Now().Unix(), 10)
+	r = httptest.NewRequest("POST", "/api/router", bytes.NewReader(body))
+	r.Header.Set(HeaderDate, date)
+	r.Header.Set("Authorization", SchemeHMAC+" Credential=operator, Signature="+SignRequest("operator-secret", "POST", "/api/router", date, body))
+	if u, err := p.Authenticate(r); err != nil || u.Name != "operator" {
+		t.Errorf("hmac: got %v, %v", u, err)
+	}
+
+	r = httptest.NewRequest("POST", "/api/router", bytes.NewReader([]byte(`{"name":"other"}`)))
+	r.Header.Set(HeaderDate, date)
+	r.Header.Set("Authorization", SchemeHMAC+" Credential=operator, Signature="+SignRequest("operator-secret", "POST", "/api/router", date, body))
+	if _, err := p.Authenticate(r); err == nil {
+		t.Error("hmac: want error for modified body")
+	}
+}
+
+func TestPolicyForward(t *testing.T) {
+	p := testPolicy()
+	r := httptest.NewRequest("PUT", "/api/router/demo", bytes.NewReader([]byte(`{"value":"a"}`)))
+	if err := p.signForward(r, p.users["operator"]); err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := p.verifyForward(r); !ok || u.Name != "operator" {
+		t.Errorf("verifyForward() = %v, %v", u, ok)
+	}
+	header := r.Header.Clone()
+
+	r = httptest.NewRequest("PUT", "/api/router/demo", bytes.NewReader([]byte(`{"value":"b"}`)))
+	r.Header = header.Clone()
+	if _, ok := p.verifyForward(r); ok {
+		t.Error("verifyForward() want false for changed body")
+	}
+	r = httptest.NewRequest("DELETE", "/api/router/demo", nil)
+	r.Header = header.Clone()
+	if _, ok := p.verifyForward(r); ok {
+		t.Error("verifyForward() want false for changed method")
+	}
+	r = httptest.NewRequest("PUT", "/api/router/demo", bytes.NewReader([]byte(`{"value":"a"}`)))
+	r.Header = header.Clone()
+	r.Header.Set(HeaderPrincipal, "viewer")
+	if _, ok := p.verifyForward(r); ok {
+		t.Error("verifyForward() want false for changed principal")
+	}
+}
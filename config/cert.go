@@ -2,6 +2,7 @@ package config
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -93,6 +94,22 @@ func loadCert(pem string, key string, dir string) (*tls.Certificate, error) {
 	return &cert, err
 }
 
+// LoadClientCA 读取校验客户端证书使用的CA
+func LoadClientCA(ca string, dir string) (*x509.CertPool, error) {
+	if !filepath.IsAbs(ca) {
+		ca = fmt.Sprintf("%s/%s", strings.TrimSuffix(dir, "/"), strings.TrimPrefix(ca, "/"))
+	}
+	data, err := ioutil.ReadFile(ca)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s:%w", ca, errorCertificateNotExit)
+	}
+	return pool, nil
+}
+
 func (c *Cert) GetCertificate(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
 	if c.certs == nil {
 		return nil, errorCertificateNotExit
@@ -36,6 +36,8 @@ type CertConfig struct {
 type UrlConfig struct {
 	ListenUrl
 	Certificate []CertConfig `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+	// ClientCA 校验客户端证书的CA，配置后客户端可以使用证书CN进行认证
+	ClientCA string `json:"client_ca,omitempty" yaml:"client_ca,omitempty"`
 }
 type ListenUrl struct {
 	ListenUrls    []string `json:"listen_urls" yaml:"listen_urls"`
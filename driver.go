@@ -13,6 +13,12 @@ type IExtenderConfigChecker interface {
 	Check(v interface{}, workers map[RequireId]IWorker) error
 }
 
+// IExtenderDisabledChecker driver可选实现，决定依赖的worker被禁用时的处理方式
+// SkipDisabled 返回true时忽略被禁用的依赖继续创建，未实现或返回false时创建失败并返回 ErrorWorkerDisabled
+type IExtenderDisabledChecker interface {
+	SkipDisabled() bool
+}
+
 type IExtenderDriver interface {
 	ConfigType() reflect.Type
 	Create(id, name string, v interface{}, workers map[RequireId]IWorker) (IWorker, error)
@@ -29,6 +29,8 @@ const (
 	envErrorLogLevel    = "ERROR_LOG_LEVEL"
 	envErrorLogExpire   = "ERROR_LOG_EXPIRE"
 	envErrorLogPeriod   = "ERROR_LOG_PERIOD"
+	envSecretKey        = "SECRET_KEY"
+	envSecretKeyFile    = "SECRET_KEY_FILE"
 )
 
 var (
@@ -223,6 +225,20 @@ func ErrorExpire() time.Duration {
 	return 7 * 24 * time.Hour
 }
 
+// SecretKey 节点持有的secret变量密钥，优先读取环境变量，其次读取密钥文件(默认为 {data_dir}/secret.key)
+// 集群内所有节点需要配置相同的密钥
+func SecretKey() ([]byte, error) {
+	if key, has := GetEnv(envSecretKey); has && key != "" {
+		return []byte(key), nil
+	}
+	path := GetDefault(envSecretKeyFile, filepath.Join(dataDirPath, "secret.key"))
+	data, err := ioutil.ReadFile(FormatPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(data), nil
+}
+
 func ExtendersDir() string {
 	return extendsBaseDir
 }
@@ -51,6 +51,12 @@ func createEnvName(envName, name string) string {
 	return fmt.Sprintf("%s_%s", envName, name)
 
 }
+
+// IsAppEnv 判断环境变量是否为程序自身的配置，如密钥、目录等
+func IsAppEnv(name string) bool {
+	return strings.HasPrefix(name, envAppName+"_")
+}
+
 func AppName() string {
 	return appName
 }
@@ -54,6 +54,8 @@ func (a *App) Default() {
 		//Env(),
 		Master(),
 		Remove(),
+		Apply(),
+		Schedule(),
 		//Plugin(),
 	)
 }
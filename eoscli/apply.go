@@ -0,0 +1,205 @@
+package eoscli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eolinker/eosc/env"
+	"github.com/eolinker/eosc/service"
+	"github.com/eolinker/eosc/utils/zip"
+	"github.com/urfave/cli/v2"
+)
+
+var CmdApply = "apply"
+
+func Apply() *cli.Command {
+	return &cli.Command{
+		Name:  CmdApply,
+		Usage: "apply desired state of workers from yaml files",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "yaml file or directory of yaml files",
+				Required: true,
+			},
+			&cli.StringSliceFlag{
+				Name:  "profession",
+				Usage: "professions covered by the files, default is the professions in the files",
+			},
+			&cli.BoolFlag{
+				Name:  "prune",
+				Usage: "delete workers with the owner label that are not in the files",
+			},
+			&cli.StringFlag{
+				Name:  "owner",
+				Usage: "owner label of the applied workers, key=value or value",
+			},
+			&cli.BoolFlag{
+				Name:  "diff",
+				Usage: "only show the changes",
+			},
+			adminAddrFlag(),
+			adminTokenFlag(),
+		},
+		Action: ApplyFunc,
+	}
+}
+
+// ApplyFunc 将yaml文件打包后提交到admin的apply接口
+func ApplyFunc(c *cli.Context) error {
+	content, err := readApplyFiles(c.StringSlice("file"))
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	if ps := c.StringSlice("profession"); len(ps) > 0 {
+		query.Set("professions", strings.Join(ps, ","))
+	}
+	if c.Bool("prune") {
+		query.Set("prune", strconv.FormatBool(true))
+	}
+	if owner := c.String("owner"); owner != "" {
+		query.Set("owner", owner)
+	}
+	path := "/apply"
+	if c.Bool("diff") {
+		path = "/apply/diff"
+	}
+	data, err := adminRequest(c, http.MethodPost, path, query, "application/zip", content)
+	fmt.Println(string(data))
+	if err != nil {
+		return fmt.Errorf("apply fail: %w", err)
+	}
+	return nil
+}
+
+func adminAddrFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "addr",
+		Usage: "admin address, default is the admin address of the leader",
+	}
+}
+
+func adminTokenFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "token",
+		Usage:   "bearer token of the admin api",
+		EnvVars: []string{"EOSC_TOKEN"},
+	}
+}
+
+func adminTenantFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "tenant",
+		Usage:   "tenant of the request, default is the default tenant",
+		EnvVars: []string{"EOSC_TENANT"},
+	}
+}
+
+// adminRequest 请求admin接口，返回响应内容，状态码不为200时返回错误
+func adminRequest(c *cli.Context, method, path string, query url.Values, contentType string, body []byte) ([]byte, error) {
+	addr := c.String("addr")
+	if addr == "" {
+		var err error
+		addr, err = leaderAdmin()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u := fmt.Sprintf("%s%s", strings.TrimSuffix(addr, "/"), path)
+	if len(query) > 0 {
+		u = fmt.Sprintf("%s?%s", u, query.Encode())
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token := c.String("token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if tenant := c.String("tenant"); tenant != "" {
+		req.Header.Set("X-Tenant", tenant)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return data, fmt.Errorf("%s", resp.Status)
+	}
+	return data, nil
+}
+
+// readApplyFiles 读取yaml文件，目录下的 .yaml、.yml 文件会全部读取，打包为zip
+func readApplyFiles(paths []string) ([]byte, error) {
+	files := make(map[string][]byte)
+	for _, p := range paths {
+		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+			if path != p && ext != ".yaml" && ext != ".yml" {
+				return nil
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(path)] = data
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no yaml file found")
+	}
+	return zip.CompressFile(files)
+}
+
+func leaderAdmin() (string, error) {
+	pid, err := readPid(env.PidFileDir())
+	if err != nil {
+		return "", err
+	}
+	client, err := createCtlServiceClient(pid)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	response, err := client.List(context.Background(), &service.ListRequest{})
+	if err != nil {
+		return "", err
+	}
+	for _, n := range response.Info {
+		if n.Leader && len(n.Admin) > 0 {
+			return n.Admin[0], nil
+		}
+	}
+	return "", fmt.Errorf("admin address of leader not found")
+}
@@ -0,0 +1,130 @@
+package eoscli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+)
+
+var CmdSchedule = "schedule"
+
+const schedulePath = "/system/schedule"
+
+func Schedule() *cli.Command {
+	return &cli.Command{
+		Name:  CmdSchedule,
+		Usage: "manage scheduled changes",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list scheduled changes",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "status",
+						Usage: "filter by status: pending, done, failed, canceled",
+					},
+					adminAddrFlag(),
+					adminTokenFlag(),
+				},
+				Action: ScheduleListFunc,
+			},
+			{
+				Name:  "add",
+				Usage: "schedule a change",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "type",
+						Usage:    "type of change: worker, patch, variable, enable, disable",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "at",
+						Usage:    "time to apply, RFC3339 or unix seconds",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "profession",
+						Usage: "profession of the worker",
+					},
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "name of the worker",
+					},
+					&cli.StringFlag{
+						Name:  "namespace",
+						Usage: "namespace of the variables",
+					},
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Usage:   "json or yaml file of the body",
+					},
+					adminAddrFlag(),
+					adminTokenFlag(),
+					adminTenantFlag(),
+				},
+				Action: ScheduleAddFunc,
+			},
+			{
+				Name:      "cancel",
+				Usage:     "cancel a pending change",
+				ArgsUsage: "id",
+				Flags: []cli.Flag{
+					adminAddrFlag(),
+					adminTokenFlag(),
+				},
+				Action: ScheduleCancelFunc,
+			},
+		},
+	}
+}
+
+func ScheduleListFunc(c *cli.Context) error {
+	query := url.Values{}
+	if status := c.String("status"); status != "" {
+		query.Set("status", status)
+	}
+	data, err := adminRequest(c, http.MethodGet, schedulePath, query, "", nil)
+	fmt.Println(string(data))
+	return err
+}
+
+// ScheduleAddFunc 创建定时变更，body 从文件读取，支持json及yaml
+func ScheduleAddFunc(c *cli.Context) error {
+	change := map[string]interface{}{
+		"type":       c.String("type"),
+		"apply_at":   c.String("at"),
+		"profession": c.String("profession"),
+		"name":       c.String("name"),
+		"namespace":  c.String("namespace"),
+	}
+	if file := c.String("file"); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		body, err := yaml.YAMLToJSON(content)
+		if err != nil {
+			return err
+		}
+		change["body"] = json.RawMessage(body)
+	}
+	content, _ := json.Marshal(change)
+	data, err := adminRequest(c, http.MethodPost, schedulePath, nil, "application/json", content)
+	fmt.Println(string(data))
+	return err
+}
+
+func ScheduleCancelFunc(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("require id of the change")
+	}
+	data, err := adminRequest(c, http.MethodDelete, fmt.Sprintf("%s/%s", schedulePath, url.PathEscape(c.Args().First())), nil, "", nil)
+	fmt.Println(string(data))
+	return err
+}
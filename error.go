@@ -8,6 +8,7 @@ var (
 	ErrorNotAllowCreateForSingleton = errors.New("not allow create for singleton profession")
 	ErrorWorkerNotExits             = errors.New("worker-data not exits")
 	ErrorWorkerNotRunning           = errors.New("worker-data not running")
+	ErrorWorkerDisabled             = errors.New("required worker disabled")
 	ErrorRegisterConflict           = errors.New("conflict of register")
 	ErrorNotGetSillForRequire       = errors.New("not get skill for require")
 	ErrorTargetNotImplementSkill    = errors.New("require of skill not implement")
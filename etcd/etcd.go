@@ -9,6 +9,7 @@ type Versions version.Versions
 type Etcd interface {
 	IsLeader() (bool, []string)
 	KV
+	Batch(ops ...*KVOperation) error
 	Watch(prefix string, handler ServiceHandler)
 	HandlerLeader(h ...ILeaderStateHandler)
 	Join(target string) error
@@ -30,6 +31,13 @@ type KV interface {
 	Delete(key string) error
 }
 
+// KVOperation 批量提交时的单个操作
+type KVOperation struct {
+	Key    string
+	Value  []byte
+	Delete bool
+}
+
 type ServiceHandler interface {
 	KV
 	Reset([]*KValue)
@@ -154,6 +154,24 @@ func (s *_Server) Delete(key string) error {
 	return err
 }
 
+// Batch 在同一个事务中提交所有操作，要么全部成功，要么全部失败
+func (s *_Server) Batch(ops ...*KVOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	txnOps := make([]clientv3.Op, 0, len(ops))
+	for _, op := range ops {
+		if op.Delete {
+			txnOps = append(txnOps, clientv3.OpDelete(op.Key))
+		} else {
+			txnOps = append(txnOps, clientv3.OpPut(op.Key, string(op.Value)))
+		}
+	}
+	ctx, _ := s.requestContext()
+	_, err := s.client.Txn(ctx).Then(txnOps...).Commit()
+	return err
+}
+
 func (s *_Server) Watch(prefix string, handler ServiceHandler) {
 	clientCh := make(chan *clientv3.Client, 1)
 	s.mu.Lock()
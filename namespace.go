@@ -6,6 +6,10 @@ const (
 	NamespaceExtender   = "extender"
 	NamespaceVariable   = "variable"
 	NamespaceCluster    = "cluster"
+	NamespaceHistory    = "history"
+	NamespaceAuth       = "auth"
+	NamespaceAudit      = "audit"
+	NamespaceSchedule   = "schedule"
 )
 
 var Namespaces = []string{
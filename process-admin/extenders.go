@@ -18,6 +18,7 @@ var (
 	ErrorExtenderNotWork         = errors.New("not work")
 	ErrorInnerExtenderCantChange = errors.New("is inner")
 	ErrorNotExist                = errors.New("not exist")
+	ErrorExist                   = errors.New("already exist")
 	ErrorDuplicatePath           = errors.New("path duplicate")
 	ErrorNotMatch                = errors.New("not match profession")
 	ErrorExtenderVersionIsChange = errors.New("the version of extender has changed")
@@ -145,20 +146,43 @@ func (e *ExtenderData) SetVersion(group, project, version string) (*ExtenderProj
 	e.locker.Lock()
 	defer e.locker.Unlock()
 
+	load, err := e.checkVersion(group, project, version)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ok := e.setVersion(group, project, version)
+	return load, ok, nil
+}
+
+// CheckVersion 校验插件版本是否可用，不修改当前版本
+func (e *ExtenderData) CheckVersion(group, project, version string) error {
+	e.locker.Lock()
+	defer e.locker.Unlock()
+	_, err := e.checkVersion(group, project, version)
+	return err
+}
+
+// commitVersion 设置已经校验过的插件版本
+func (e *ExtenderData) commitVersion(group, project, version string) bool {
+	e.locker.Lock()
+	defer e.locker.Unlock()
+	return e.setVersion(group, project, version)
+}
+
+func (e *ExtenderData) checkVersion(group, project, version string) (*ExtenderProject, error) {
 	if extends.IsInner(group, project) {
-		return nil, false, fmt.Errorf("%s:%s %w", group, project, ErrorInnerExtenderCantChange)
+		return nil, fmt.Errorf("%s:%s %w", group, project, ErrorInnerExtenderCantChange)
 	}
 
 	load, err := e.load(group, project, version)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 	if !load.isWork {
-		return nil, false, fmt.Errorf("%s:%s:%s %w", group, project, version, ErrorExtenderNotWork)
+		return nil, fmt.Errorf("%s:%s:%s %w", group, project, version, ErrorExtenderNotWork)
 	}
-
-	ok := e.setVersion(group, project, version)
-	return load, ok, nil
+	return load, nil
 }
 
 func (e *ExtenderData) load(group, project, version string) (*ExtenderProject, error) {
@@ -0,0 +1,174 @@
+package process_admin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/eolinker/eosc"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ApplyOwnerLabel owner参数只有值时使用的标签名
+const ApplyOwnerLabel = "owner"
+
+// ApplyResult 声明式应用的变更，professions 为本次应用覆盖的profession
+type ApplyResult struct {
+	Professions []string      `json:"professions"`
+	Prune       bool          `json:"prune"`
+	Owner       string        `json:"owner,omitempty"`
+	DryRun      bool          `json:"dry_run"`
+	Workers     []*ImportDiff `json:"workers"`
+}
+
+type applyPlan struct {
+	result *ApplyResult
+	ops    []*TransactionOperation
+}
+
+// apply 以请求中的数据作为指定profession的期望状态，按依赖顺序在副本上创建及更新worker，全部通过后提交副本
+// prune=true 时删除带有owner标签但不在数据中的worker
+func (oe *ExportApi) apply(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	plan, err := oe.applyPlan(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	if ie := oe.authorize(r, nil, plan.ops); ie != nil {
+		return http.StatusForbidden, nil, nil, ie
+	}
+	clone := oe.workers.Clone()
+	ids, changed, ie := validateOperations(clone, plan.ops)
+	if ie != nil {
+		return http.StatusBadRequest, nil, nil, ie
+	}
+	if isDryRun(r) {
+		plan.result.DryRun = true
+		return http.StatusOK, nil, nil, plan.result
+	}
+	return http.StatusOK, nil, oe.workers.commitEvents(clone, ids, changed), plan.result
+}
+
+// applyDiff 预览apply的变更，不产生事件
+func (oe *ExportApi) applyDiff(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	plan, err := oe.applyPlan(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	if ie := oe.authorize(r, nil, plan.ops); ie != nil {
+		return http.StatusForbidden, nil, nil, ie
+	}
+	if _, _, ie := validateOperations(oe.workers.Clone(), plan.ops); ie != nil {
+		return http.StatusBadRequest, nil, nil, ie
+	}
+	plan.result.DryRun = true
+	return http.StatusOK, nil, nil, plan.result
+}
+
+func (oe *ExportApi) applyPlan(r *http.Request) (*applyPlan, error) {
+	query := r.URL.Query()
+	prune := query.Get("prune") == "true"
+	ownerKey, ownerValue := parseOwner(query.Get("owner"))
+	if prune && ownerValue == "" {
+		return nil, fmt.Errorf("prune require owner label")
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	bundle, err := readImportBundle(data)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := make(map[string]bool)
+	for _, p := range strings.Split(query.Get("professions"), ",") {
+		if p = strings.TrimSpace(strings.ToLower(p)); p != "" {
+			scope[p] = true
+		}
+	}
+	limited := len(scope) > 0
+	applied := make(map[string]bool, len(bundle.workers))
+	for _, item := range bundle.workers {
+		if limited && !scope[item.Profession] {
+			return nil, fmt.Errorf("%s@%s:profession not in %s", item.Name, item.Profession, query.Get("professions"))
+		}
+		if _, has := oe.profession.Get(item.Profession); !has {
+			return nil, fmt.Errorf("%s:%w", item.Profession, eosc.ErrorProfessionNotExist)
+		}
+		scope[item.Profession] = true
+		id, _ := eosc.ToWorkerId(item.Name, item.Profession)
+		applied[id] = true
+		if ownerValue != "" {
+			setLabel(item.Body, ownerKey, ownerValue)
+		}
+	}
+
+	result := &ApplyResult{
+		Professions: make([]string, 0, len(scope)),
+		Prune:       prune,
+	}
+	if ownerValue != "" {
+		result.Owner = fmt.Sprintf("%s=%s", ownerKey, ownerValue)
+	}
+	for p := range scope {
+		result.Professions = append(result.Professions, p)
+	}
+	sort.Strings(result.Professions)
+
+	ops, diffs := oe.workerDiff(sortImportWorkers(oe.profession.Sort(), bundle.workers), "", false)
+	if prune {
+		for _, id := range oe.pruneOrder(scope, applied, ownerKey, ownerValue) {
+			profession, name, _ := eosc.SplitWorkerId(id)
+			ops = append(ops, &TransactionOperation{Action: TransactionDelete, Profession: profession, Name: name})
+			diffs = append(diffs, &ImportDiff{Id: id, Action: DiffDelete})
+		}
+	}
+	result.Workers = diffs
+	return &applyPlan{result: result, ops: ops}, nil
+}
+
+// pruneOrder 返回默认租户下范围内带有owner标签且不在keep中的worker，引用方排在被引用方前面
+func (oe *ExportApi) pruneOrder(scope, keep map[string]bool, key, value string) []string {
+	ids := make([]string, 0)
+	for profession, list := range oe.workers.Export("") {
+		if !scope[profession] {
+			continue
+		}
+		for _, w := range list {
+			if keep[w.config.Id] {
+				continue
+			}
+			if v, has := w.Labels()[key]; has && v == value {
+				ids = append(ids, w.config.Id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return leafFirst(ids, oe.workers.requireManager)
+}
+
+// parseOwner 解析 key=value 格式的owner标签，只有值时使用默认的标签名
+func parseOwner(owner string) (string, string) {
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return "", ""
+	}
+	if i := strings.Index(owner, "="); i >= 0 {
+		return strings.TrimSpace(owner[:i]), strings.TrimSpace(owner[i+1:])
+	}
+	return ApplyOwnerLabel, owner
+}
+
+func setLabel(body map[string]interface{}, key, value string) {
+	labels, ok := body[workerLabelsField].(map[string]interface{})
+	if !ok {
+		labels = make(map[string]interface{})
+		body[workerLabelsField] = labels
+	}
+	labels[key] = value
+}
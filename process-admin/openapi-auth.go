@@ -0,0 +1,259 @@
+package process_admin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/auth"
+	"github.com/eolinker/eosc/log"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuthUserArg 用户的请求参数，token 只在提交时使用明文，保存的是hash
+type AuthUserArg struct {
+	Name    string   `json:"name" yaml:"name"`
+	Token   string   `json:"token" yaml:"token"`
+	Secret  string   `json:"secret" yaml:"secret"`
+	CN      string   `json:"cn" yaml:"cn"`
+	Roles   []string `json:"roles" yaml:"roles"`
+	Tenants []string `json:"tenants" yaml:"tenants"`
+}
+
+// AuthApi 管理open api的用户及角色，数据保存在 NamespaceAuth 下，由master负责认证及鉴权
+type AuthApi struct {
+	users     map[string]*auth.User
+	roles     map[string]*auth.Role
+	hasSecret bool
+}
+
+func NewAuthApi(data map[string][]byte) *AuthApi {
+	a := &AuthApi{
+		users: make(map[string]*auth.User),
+		roles: make(map[string]*auth.Role),
+	}
+	for key, value := range data {
+		switch {
+		case key == auth.KeyForwardSecret:
+			a.hasSecret = true
+		case strings.HasPrefix(key, auth.KeyUserPrefix):
+			u := new(auth.User)
+			if err := json.Unmarshal(value, u); err != nil {
+				log.Warn("read auth user:", key, ":", err)
+				continue
+			}
+			a.users[u.Name] = u
+		case strings.HasPrefix(key, auth.KeyRolePrefix):
+			r := new(auth.Role)
+			if err := json.Unmarshal(value, r); err != nil {
+				log.Warn("read auth role:", key, ":", err)
+				continue
+			}
+			a.roles[r.Name] = r
+		}
+	}
+	return a
+}
+
+// authorize 校验请求用户对resource的权限，master只校验了第一级路径，批量接口需要按每个操作的profession再次鉴权
+// 未配置用户时不校验
+func (oe *AuthApi) authorize(r *http.Request, method, resource string) error {
+	if oe == nil || len(oe.users) == 0 {
+		return nil
+	}
+	u, has := oe.users[r.Header.Get(auth.HeaderPrincipal)]
+	if !has || !auth.Allow(oe.roles, u, method, resource) {
+		return fmt.Errorf("%s %s:%s", method, resource, http.StatusText(http.StatusForbidden))
+	}
+	return nil
+}
+
+func (oe *AuthApi) Register(router *httprouter.Router) {
+	router.GET("/auth/users", open_api.CreateHandleFunc(oe.listUsers))
+	router.GET("/auth/user/:name", open_api.CreateHandleFunc(oe.getUser))
+	router.POST("/auth/user/:name", open_api.CreateHandleFunc(oe.setUser))
+	router.PUT("/auth/user/:name", open_api.CreateHandleFunc(oe.setUser))
+	router.DELETE("/auth/user/:name", open_api.CreateHandleFunc(oe.deleteUser))
+
+	router.GET("/auth/roles", open_api.CreateHandleFunc(oe.listRoles))
+	router.GET("/auth/role/:name", open_api.CreateHandleFunc(oe.getRole))
+	router.POST("/auth/role/:name", open_api.CreateHandleFunc(oe.setRole))
+	router.PUT("/auth/role/:name", open_api.CreateHandleFunc(oe.setRole))
+	router.DELETE("/auth/role/:name", open_api.CreateHandleFunc(oe.deleteRole))
+}
+
+func (oe *AuthApi) listUsers(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	users := make([]*auth.User, 0, len(oe.users))
+	for _, u := range oe.users {
+		users = append(users, u.Masked())
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+	return http.StatusOK, nil, nil, users
+}
+
+func (oe *AuthApi) getUser(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	name := params.ByName("name")
+	u, has := oe.users[name]
+	if !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("user %s %s", name, ErrorNotExist)
+	}
+	return http.StatusOK, nil, nil, u.Masked()
+}
+
+func (oe *AuthApi) setUser(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	name := params.ByName("name")
+	decoder, err := GetData(r)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	arg := new(AuthUserArg)
+	if err := decoder.UnMarshal(arg); err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	for _, role := range arg.Roles {
+		if _, has := oe.roles[role]; !has {
+			return http.StatusBadRequest, nil, nil, fmt.Sprintf("role %s %s", role, ErrorNotExist)
+		}
+	}
+
+	u := &auth.User{Name: name, CN: arg.CN, Roles: arg.Roles, Tenants: arg.Tenants}
+	org, has := oe.users[name]
+	// 未提交或者提交的是掩码时保留原来的值
+	switch {
+	case arg.Token != "" && arg.Token != auth.MaskedValue:
+		u.TokenHash = auth.HashToken(arg.Token)
+	case has:
+		u.TokenHash = org.TokenHash
+	}
+	switch {
+	case arg.Secret != "" && arg.Secret != auth.MaskedValue:
+		u.Secret = arg.Secret
+	case has:
+		u.Secret = org.Secret
+	}
+	if u.TokenHash == "" && u.Secret == "" && u.CN == "" {
+		return http.StatusBadRequest, nil, nil, "require token, secret or cn"
+	}
+	for _, other := range oe.users {
+		if other.Name == name {
+			continue
+		}
+		if u.TokenHash != "" && other.TokenHash == u.TokenHash {
+			return http.StatusBadRequest, nil, nil, "token is used by other user"
+		}
+		if u.CN != "" && other.CN == u.CN {
+			return http.StatusBadRequest, nil, nil, fmt.Sprintf("cn %s is used by %s", u.CN, other.Name)
+		}
+	}
+
+	data, _ := json.Marshal(u)
+	oe.users[name] = u
+	events = []*open_api.EventResponse{{
+		Event:     eosc.EventSet,
+		Namespace: eosc.NamespaceAuth,
+		Key:       auth.UserKey(name),
+		Data:      data,
+	}}
+	if !oe.hasSecret {
+		// 第一次添加用户时生成节点之间转发使用的密钥
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		oe.hasSecret = true
+		events = append(events, &open_api.EventResponse{
+			Event:     eosc.EventSet,
+			Namespace: eosc.NamespaceAuth,
+			Key:       auth.KeyForwardSecret,
+			Data:      []byte(hex.EncodeToString(secret)),
+		})
+	}
+	return http.StatusOK, nil, events, u.Masked()
+}
+
+func (oe *AuthApi) deleteUser(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	name := params.ByName("name")
+	u, has := oe.users[name]
+	if !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("user %s %s", name, ErrorNotExist)
+	}
+	delete(oe.users, name)
+	return http.StatusOK, nil, []*open_api.EventResponse{{
+		Event:     eosc.EventDel,
+		Namespace: eosc.NamespaceAuth,
+		Key:       auth.UserKey(name),
+	}}, u.Masked()
+}
+
+func (oe *AuthApi) listRoles(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	roles := make([]*auth.Role, 0, len(oe.roles))
+	for _, role := range oe.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	return http.StatusOK, nil, nil, roles
+}
+
+func (oe *AuthApi) getRole(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	name := params.ByName("name")
+	role, has := oe.roles[name]
+	if !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("role %s %s", name, ErrorNotExist)
+	}
+	return http.StatusOK, nil, nil, role
+}
+
+func (oe *AuthApi) setRole(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	name := params.ByName("name")
+	decoder, err := GetData(r)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	role := new(auth.Role)
+	if err := decoder.UnMarshal(role); err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	role.Name = name
+	for i, rule := range role.Rules {
+		if rule == nil || len(rule.Resources) == 0 || len(rule.Methods) == 0 {
+			return http.StatusBadRequest, nil, nil, fmt.Sprintf("rules[%d]:require resources and methods", i)
+		}
+	}
+	data, _ := json.Marshal(role)
+	oe.roles[name] = role
+	return http.StatusOK, nil, []*open_api.EventResponse{{
+		Event:     eosc.EventSet,
+		Namespace: eosc.NamespaceAuth,
+		Key:       auth.RoleKey(name),
+		Data:      data,
+	}}, role
+}
+
+func (oe *AuthApi) deleteRole(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	name := params.ByName("name")
+	role, has := oe.roles[name]
+	if !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("role %s %s", name, ErrorNotExist)
+	}
+	for _, u := range oe.users {
+		for _, rn := range u.Roles {
+			if rn == name {
+				return http.StatusForbidden, nil, nil, fmt.Sprintf("role %s is used by %s", name, u.Name)
+			}
+		}
+	}
+	delete(oe.roles, name)
+	return http.StatusOK, nil, []*open_api.EventResponse{{
+		Event:     eosc.EventDel,
+		Namespace: eosc.NamespaceAuth,
+		Key:       auth.RoleKey(name),
+	}}, role
+}
@@ -0,0 +1,290 @@
+package process_admin
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eolinker/eosc"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/eolinker/eosc/professions"
+	"github.com/julienschmidt/httprouter"
+)
+
+const openApiVersion = "3.0.3"
+
+// apiRoute open api 文档中的接口描述，新增路由时需要同时在 adminRoutes 中添加
+type apiRoute struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	Query   []string
+	// Body 请求体的schema，为空时表示没有请求体
+	Body string
+}
+
+var (
+	routeParamReg = regexp.MustCompile(`:([^/]+)`)
+	schemaNameReg = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+var adminRoutes = []*apiRoute{
+	{Method: http.MethodGet, Path: "/api", Tag: "worker", Summary: "search workers of all professions", Query: []string{"q", "driver", "name", "name~", "sort", "offset", "limit", "selector"}},
+	{Method: http.MethodGet, Path: "/api/:profession", Tag: "worker", Summary: "list workers of profession", Query: []string{"q", "driver", "name", "name~", "sort", "offset", "limit", "selector"}},
+	{Method: http.MethodPost, Path: "/api/:profession", Tag: "worker", Summary: "create worker", Query: []string{"dry_run"}, Body: "WorkerBody"},
+	{Method: http.MethodPatch, Path: "/api/:profession", Tag: "worker", Summary: "patch workers matched by label selector", Query: []string{"selector", "dry_run"}, Body: "Object"},
+	{Method: http.MethodDelete, Path: "/api/:profession", Tag: "worker", Summary: "delete workers matched by label selector", Query: []string{"selector", "cascade", "dry_run"}},
+	{Method: http.MethodGet, Path: "/api/:profession/:name", Tag: "worker", Summary: "get worker"},
+	{Method: http.MethodPut, Path: "/api/:profession/:name", Tag: "worker", Summary: "save worker", Query: []string{"dry_run"}, Body: "WorkerBody"},
+	{Method: http.MethodPost, Path: "/api/:profession/:name", Tag: "worker", Summary: "save worker", Query: []string{"dry_run"}, Body: "WorkerBody"},
+	{Method: http.MethodPatch, Path: "/api/:profession/:name", Tag: "worker", Summary: "patch worker, null value removes the field", Query: []string{"dry_run"}, Body: "Object"},
+	{Method: http.MethodDelete, Path: "/api/:profession/:name", Tag: "worker", Summary: "delete worker", Query: []string{"cascade", "dry_run"}},
+	{Method: http.MethodGet, Path: "/api/:profession/:name/history", Tag: "worker", Summary: "list revisions of worker"},
+	{Method: http.MethodGet, Path: "/api/:profession/:name/history/:rev", Tag: "worker", Summary: "get revision of worker"},
+	{Method: http.MethodPost, Path: "/api/:profession/:name/rollback/:rev", Tag: "worker", Summary: "rollback worker to revision"},
+	{Method: http.MethodPost, Path: "/api/:profession/:name/disable", Tag: "worker", Summary: "disable worker and stop its instance"},
+	{Method: http.MethodPost, Path: "/api/:profession/:name/enable", Tag: "worker", Summary: "enable disabled worker"},
+	{Method: http.MethodPost, Path: "/api/:profession/:name/rename", Tag: "worker", Summary: "rename worker and rewrite references", Query: []string{"dry_run"}, Body: "Object"},
+	{Method: http.MethodGet, Path: "/api/:profession/:name/graph", Tag: "graph", Summary: "dependency graph of worker", Query: []string{"depth", "format"}},
+	{Method: http.MethodGet, Path: "/graph", Tag: "graph", Summary: "dependency graph of cluster", Query: []string{"format"}},
+	{Method: http.MethodGet, Path: "/status/workers", Tag: "worker", Summary: "runtime status of workers reported by the worker process of the leader node"},
+
+	{Method: http.MethodGet, Path: "/profession", Tag: "profession", Summary: "list professions"},
+	{Method: http.MethodGet, Path: "/profession/:profession", Tag: "profession", Summary: "get profession"},
+	{Method: http.MethodGet, Path: "/profession/:profession/drivers", Tag: "profession", Summary: "list drivers of profession"},
+	{Method: http.MethodPut, Path: "/profession/:profession/drivers", Tag: "profession", Summary: "reset drivers of profession", Body: "Array"},
+	{Method: http.MethodPost, Path: "/profession/:profession/drivers", Tag: "profession", Summary: "reset drivers of profession", Body: "Array"},
+	{Method: http.MethodGet, Path: "/profession/:profession/driver", Tag: "profession", Summary: "get driver with config schema", Query: []string{"name"}},
+	{Method: http.MethodPut, Path: "/profession/:profession/driver", Tag: "profession", Summary: "update driver", Body: "Object"},
+	{Method: http.MethodPost, Path: "/profession/:profession/driver", Tag: "profession", Summary: "add driver", Body: "Object"},
+	{Method: http.MethodDelete, Path: "/profession/:profession/driver", Tag: "profession", Summary: "delete driver", Query: []string{"name"}},
+	{Method: http.MethodGet, Path: "/profession/:profession/skill", Tag: "profession", Summary: "list workers implement skill", Query: []string{"skill"}},
+
+	{Method: http.MethodGet, Path: "/setting/:name", Tag: "setting", Summary: "get setting"},
+	{Method: http.MethodPost, Path: "/setting/:name", Tag: "setting", Summary: "set setting, batch setting creates a worker for each item", Query: []string{"dry_run"}, Body: "Object"},
+	{Method: http.MethodPut, Path: "/setting/:name", Tag: "setting", Summary: "set setting, batch setting creates a worker for each item", Query: []string{"dry_run"}, Body: "Object"},
+
+	{Method: http.MethodGet, Path: "/variable", Tag: "variable", Summary: "list variables"},
+	{Method: http.MethodGet, Path: "/variable/:namespace", Tag: "variable", Summary: "list variables of namespace"},
+	{Method: http.MethodGet, Path: "/variable/:namespace/:key", Tag: "variable", Summary: "get variable"},
+	{Method: http.MethodPost, Path: "/variable/:namespace", Tag: "variable", Summary: "set variables of namespace", Query: []string{"secret"}, Body: "Object"},
+	{Method: http.MethodPut, Path: "/variable/:namespace", Tag: "variable", Summary: "set variables of namespace", Query: []string{"secret"}, Body: "Object"},
+	{Method: http.MethodDelete, Path: "/variable/:namespace", Tag: "variable", Summary: "delete variables of namespace", Query: []string{"force"}},
+	{Method: http.MethodPut, Path: "/variable/:namespace/:key", Tag: "variable", Summary: "set variable", Body: "Object"},
+	{Method: http.MethodDelete, Path: "/variable/:namespace/:key", Tag: "variable", Summary: "delete variable", Query: []string{"force"}},
+	{Method: http.MethodGet, Path: "/variable/:namespace/:key/usage", Tag: "variable", Summary: "list workers using variable"},
+
+	{Method: http.MethodGet, Path: "/extender", Tag: "extender", Summary: "list extenders"},
+	{Method: http.MethodPut, Path: "/extender", Tag: "extender", Summary: "set extenders", Body: "Object"},
+	{Method: http.MethodPost, Path: "/extender", Tag: "extender", Summary: "set extenders", Body: "Object"},
+	{Method: http.MethodGet, Path: "/extender/:id", Tag: "extender", Summary: "get extender"},
+	{Method: http.MethodDelete, Path: "/extender/:id", Tag: "extender", Summary: "delete extender"},
+	{Method: http.MethodGet, Path: "/extender/:id/:name", Tag: "extender", Summary: "render of extender driver"},
+
+	{Method: http.MethodGet, Path: "/export", Tag: "export", Summary: "export all config as zip", Query: []string{"selector"}},
+	{Method: http.MethodPost, Path: "/import", Tag: "export", Summary: "import config from zip or yaml", Query: []string{"mode", "dry_run"}},
+	{Method: http.MethodPost, Path: "/apply", Tag: "export", Summary: "apply desired state of professions", Query: []string{"professions", "prune", "owner", "dry_run"}},
+	{Method: http.MethodPost, Path: "/apply/diff", Tag: "export", Summary: "preview changes of apply", Query: []string{"professions", "prune", "owner"}},
+	{Method: http.MethodPost, Path: "/transaction", Tag: "transaction", Summary: "apply operations atomically", Body: "Object"},
+	{Method: http.MethodPost, Path: "/batch", Tag: "transaction", Summary: "apply operations atomically", Body: "Object"},
+
+	{Method: http.MethodGet, Path: "/auth/users", Tag: "auth", Summary: "list users"},
+	{Method: http.MethodGet, Path: "/auth/user/:name", Tag: "auth", Summary: "get user"},
+	{Method: http.MethodPost, Path: "/auth/user/:name", Tag: "auth", Summary: "set user", Body: "Object"},
+	{Method: http.MethodPut, Path: "/auth/user/:name", Tag: "auth", Summary: "set user", Body: "Object"},
+	{Method: http.MethodDelete, Path: "/auth/user/:name", Tag: "auth", Summary: "delete user"},
+	{Method: http.MethodGet, Path: "/auth/roles", Tag: "auth", Summary: "list roles"},
+	{Method: http.MethodGet, Path: "/auth/role/:name", Tag: "auth", Summary: "get role"},
+	{Method: http.MethodPost, Path: "/auth/role/:name", Tag: "auth", Summary: "set role", Body: "Object"},
+	{Method: http.MethodPut, Path: "/auth/role/:name", Tag: "auth", Summary: "set role", Body: "Object"},
+	{Method: http.MethodDelete, Path: "/auth/role/:name", Tag: "auth", Summary: "delete role"},
+
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "doc", Summary: "this document"},
+}
+
+// OpenApiDoc 生成admin接口的OpenAPI文档，包含当前已安装的driver的配置schema
+type OpenApiDoc struct {
+	professions professions.IProfessions
+	router      *httprouter.Router
+}
+
+func NewOpenApiDoc(professions professions.IProfessions) *OpenApiDoc {
+	return &OpenApiDoc{professions: professions}
+}
+
+func (oe *OpenApiDoc) Register(router *httprouter.Router) {
+	oe.router = router
+	router.GET("/openapi.json", open_api.CreateHandleFunc(oe.document))
+}
+
+func (oe *OpenApiDoc) document(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	schemas := map[string]interface{}{
+		"Object": map[string]interface{}{"type": "object"},
+		"Array":  map[string]interface{}{"type": "array", "items": map[string]interface{}{}},
+		"WorkerBase": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name":        map[string]interface{}{"type": "string"},
+				"driver":      map[string]interface{}{"type": "string"},
+				"description": map[string]interface{}{"type": "string"},
+				"labels":      map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+				"shared":      map[string]interface{}{"type": "boolean", "description": "allow workers of other tenants to require this worker"},
+			},
+		},
+		"WorkerBody": map[string]interface{}{
+			"description": "worker config, see the driver schemas of profession",
+			"allOf":       []interface{}{ref("WorkerBase")},
+		},
+	}
+	paths := make(map[string]map[string]interface{})
+	for _, route := range adminRoutes {
+		// 只输出实际注册了的路由
+		if h, _, _ := oe.router.Lookup(route.Method, routeParamReg.ReplaceAllString(route.Path, "_")); h == nil {
+			continue
+		}
+		path := routeParamReg.ReplaceAllString(route.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(route.Method)] = operation(route.Tag, route.Summary, pathParams(route.Path), route.Query, route.Body)
+	}
+
+	tags := []interface{}{}
+	for _, p := range oe.professions.Sort() {
+		refs := make([]interface{}, 0)
+		for _, d := range p.GetDrivers() {
+			driver, has := p.GetDriver(d.Name)
+			if !has {
+				continue
+			}
+			sc := configSchema(driver.ConfigType())
+			if sc == nil {
+				continue
+			}
+			name := schemaName(p.Name, d.Name)
+			schemas[name] = map[string]interface{}{
+				"description": d.Desc,
+				"allOf": []interface{}{
+					ref("WorkerBase"),
+					sc,
+				},
+			}
+			refs = append(refs, ref(name))
+		}
+		tags = append(tags, map[string]interface{}{"name": p.Name, "description": p.Desc})
+		if len(refs) == 0 {
+			continue
+		}
+		// 每个profession单独输出接口，请求体为该profession下所有driver的配置
+		workerBody := map[string]interface{}{"oneOf": refs}
+		base := "/api/" + p.Name
+		item := base + "/{name}"
+		if p.Mod == eosc.ProfessionConfig_Singleton {
+			paths[item] = map[string]interface{}{
+				"get": operation(p.Name, "get "+p.Name, []string{"name"}, nil, ""),
+				"put": operationWithBody(p.Name, "save "+p.Name, []string{"name"}, []string{"dry_run"}, workerBody),
+			}
+			continue
+		}
+		paths[base] = map[string]interface{}{
+			"get":    operation(p.Name, "list "+p.Name, nil, []string{"q", "driver", "name", "name~", "sort", "offset", "limit", "selector"}, ""),
+			"post":   operationWithBody(p.Name, "create "+p.Name, nil, []string{"dry_run"}, workerBody),
+			"patch":  operation(p.Name, "patch "+p.Name+" matched by label selector", nil, []string{"selector", "dry_run"}, "Object"),
+			"delete": operation(p.Name, "delete "+p.Name+" matched by label selector", nil, []string{"selector", "cascade", "dry_run"}, ""),
+		}
+		paths[item] = map[string]interface{}{
+			"get":    operation(p.Name, "get "+p.Name, []string{"name"}, nil, ""),
+			"put":    operationWithBody(p.Name, "save "+p.Name, []string{"name"}, []string{"dry_run"}, workerBody),
+			"delete": operation(p.Name, "delete "+p.Name, []string{"name"}, []string{"cascade", "dry_run"}, ""),
+		}
+	}
+
+	return http.StatusOK, nil, nil, map[string]interface{}{
+		"openapi": openApiVersion,
+		"info": map[string]interface{}{
+			"title":   "eosc admin api",
+			"version": "1.0",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+func operation(tag, summary string, pathParams, query []string, body string) map[string]interface{} {
+	var requestBody interface{}
+	if body != "" {
+		requestBody = ref(body)
+	}
+	return operationWithBody(tag, summary, pathParams, query, requestBody)
+}
+
+func operationWithBody(tag, summary string, pathParams, query []string, body interface{}) map[string]interface{} {
+	parameters := make([]interface{}, 0, len(pathParams)+len(query)+1)
+	for _, name := range pathParams {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	// 租户可以通过header指定，也可以使用 /t/{tenant} 前缀
+	parameters = append(parameters, map[string]interface{}{
+		"name":   HeaderTenant,
+		"in":     "header",
+		"schema": map[string]interface{}{"type": "string"},
+	})
+	query = append([]string(nil), query...)
+	sort.Strings(query)
+	for _, name := range query {
+		parameters = append(parameters, map[string]interface{}{
+			"name":   name,
+			"in":     "query",
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	op := map[string]interface{}{
+		"tags":       []string{tag},
+		"summary":    summary,
+		"parameters": parameters,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "success",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+	if body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": body},
+				"application/yaml": map[string]interface{}{"schema": body},
+			},
+		}
+	}
+	return op
+}
+
+func pathParams(path string) []string {
+	rs := make([]string, 0)
+	for _, m := range routeParamReg.FindAllStringSubmatch(path, -1) {
+		rs = append(rs, m[1])
+	}
+	return rs
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func schemaName(profession, driver string) string {
+	return schemaNameReg.ReplaceAllString(profession+"."+driver, "_")
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/eolinker/eosc/log"
 	open_api "github.com/eolinker/eosc/open-api"
 	"github.com/eolinker/eosc/professions"
+	"github.com/eolinker/eosc/utils/labels"
 	"github.com/eolinker/eosc/utils/zip"
 	"github.com/julienschmidt/httprouter"
 	"gopkg.in/yaml.v3"
@@ -16,18 +17,28 @@ type ExportApi struct {
 	extenders  *ExtenderData
 	workers    *Workers
 	profession professions.IProfessions
+	auth       *AuthApi
 }
 
-func NewExportApi(extenders *ExtenderData, profession professions.IProfessions, workers *Workers) *ExportApi {
-	return &ExportApi{extenders: extenders, workers: workers, profession: profession}
+func NewExportApi(extenders *ExtenderData, profession professions.IProfessions, workers *Workers, auth *AuthApi) *ExportApi {
+	return &ExportApi{extenders: extenders, workers: workers, profession: profession, auth: auth}
 }
 
 func (oe *ExportApi) Register(router *httprouter.Router) {
 	router.GET("/export", open_api.CreateHandleFunc(oe.export))
+	router.POST("/import", open_api.CreateHandleFunc(oe.importData))
+	router.POST("/apply", open_api.CreateHandleFunc(oe.apply))
+	router.POST("/apply/diff", open_api.CreateHandleFunc(oe.applyDiff))
 
 }
+
+// export 导出所有配置，selector 不为空时只导出标签匹配的worker
 func (oe *ExportApi) export(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
-	workerData := oe.allWorker()
+	selector, err := labels.Parse(r.URL.Query().Get("selector"))
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	workerData := oe.allWorker(tenantOf(r), selector)
 	extenderList := oe.extenders.versions()
 	professionList := oe.profession.List()
 
@@ -78,15 +89,19 @@ func getExportData(value map[string][]interface{}) map[string][]byte {
 	return data
 }
 
-func (oe *ExportApi) allWorker() map[string][]interface{} {
-	ps := oe.workers.Export()
+func (oe *ExportApi) allWorker(tenant string, selector labels.Selector) map[string][]interface{} {
+	ps := oe.workers.Export(tenant)
 	data := make(map[string][]interface{})
 	for key, pl := range ps {
 		list := make([]interface{}, 0, len(pl))
 		for _, p := range pl {
-			list = append(list, p.Detail())
+			if selector.Matches(p.config.Labels) {
+				list = append(list, p.Detail())
+			}
+		}
+		if len(list) > 0 {
+			data[key] = list
 		}
-		data[key] = list
 	}
 	return data
 }
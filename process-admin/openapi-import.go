@@ -0,0 +1,503 @@
+package process_admin
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/log"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/eolinker/eosc/professions"
+	"github.com/ghodss/yaml"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	ImportModeMerge   = "merge"
+	ImportModeReplace = "replace"
+
+	DiffCreate = "create"
+	DiffUpdate = "update"
+	DiffDelete = "delete"
+)
+
+const (
+	importKeyExtenders   = "extenders"
+	importKeyProfessions = "professions"
+)
+
+var zipMagic = []byte("PK\x03\x04")
+
+// ImportDiff 导入时单个对象的变更
+type ImportDiff struct {
+	Id      string `json:"id"`
+	Action  string `json:"action"`
+	Version string `json:"version,omitempty"`
+	Origin  string `json:"origin,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ImportResult struct {
+	Mode      string        `json:"mode"`
+	DryRun    bool          `json:"dry_run"`
+	Extenders []*ImportDiff `json:"extenders"`
+	Workers   []*ImportDiff `json:"workers"`
+}
+
+// ImportError 导入失败时返回，id为出错的对象
+type ImportError struct {
+	Id    string `json:"id"`
+	Error string `json:"error"`
+}
+
+type importBundle struct {
+	extenders map[string]string
+	workers   []*TransactionOperation
+}
+
+// importData 导入 /export 导出的zip包或者同样结构的yaml，worker导入到请求的租户下
+// 导入时按照profession顺序及worker之间的依赖顺序在副本上写入worker，全部校验通过后再设置插件版本并提交副本，replace模式下会删除租户下导入数据中不存在的worker
+// 导入包中的插件需要加载后才能校验使用它的worker，此时导入失败，需要先单独导入插件
+func (oe *ExportApi) importData(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	query := r.URL.Query()
+	mode := strings.ToLower(query.Get("mode"))
+	if mode == "" {
+		mode = ImportModeMerge
+	}
+	if mode != ImportModeMerge && mode != ImportModeReplace {
+		return http.StatusBadRequest, nil, nil, fmt.Sprintf("invalid mode:%s", mode)
+	}
+	dryRun := query.Get("dry_run") == "true"
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	r.Body.Close()
+	bundle, err := readImportBundle(data)
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+
+	result := &ImportResult{
+		Mode:      mode,
+		DryRun:    dryRun,
+		Extenders: oe.extenderDiff(bundle.extenders),
+	}
+	tenant := tenantOf(r)
+	if tenant != "" && len(result.Extenders) > 0 {
+		// 插件为集群级别的配置，不区分租户
+		return http.StatusForbidden, nil, nil, fmt.Sprintf("%s not support tenant", importKeyExtenders)
+	}
+	for _, op := range bundle.workers {
+		scopeOperation(tenant, op)
+	}
+
+	workerOps, diffs := oe.workerDiff(sortImportWorkers(oe.profession.Sort(), bundle.workers), tenant, mode == ImportModeReplace)
+	result.Workers = diffs
+	if ie := oe.authorize(r, result.Extenders, workerOps); ie != nil {
+		return http.StatusForbidden, nil, nil, ie
+	}
+
+	// 导入包中的插件尚未加载时，使用新插件的worker无法校验，dry run 在差异中记录，实际导入时失败
+	clone := oe.workers.Clone()
+	ids := make([]string, 0, len(workerOps))
+	changed := make([]*WorkerInfo, 0, len(workerOps))
+	for i, op := range workerOps {
+		es, worker, err := applyOperation(clone, op)
+		if err != nil {
+			err = extenderRequired(err, result.Extenders)
+			if !dryRun || !errors.Is(err, eosc.ErrorDriverNotExist) {
+				return http.StatusBadRequest, nil, nil, newImportError(op, err)
+			}
+			result.Workers[i].Error = err.Error()
+			continue
+		}
+		ids = append(ids, es[0].Key)
+		changed = append(changed, worker)
+	}
+	if dryRun {
+		return http.StatusOK, nil, nil, result
+	}
+
+	// 全部校验通过后才修改插件版本和worker，失败时不产生任何事件
+	for _, ed := range result.Extenders {
+		group, project := readProject(ed.Id)
+		if err := oe.extenders.CheckVersion(group, project, ed.Version); err != nil {
+			return http.StatusBadRequest, nil, nil, &ImportError{Id: ed.Id, Error: err.Error()}
+		}
+	}
+
+	events = make([]*open_api.EventResponse, 0, len(result.Extenders)+len(workerOps))
+	for _, ed := range result.Extenders {
+		group, project := readProject(ed.Id)
+		if oe.extenders.commitVersion(group, project, ed.Version) {
+			events = append(events, &open_api.EventResponse{
+				Event:     eosc.EventSet,
+				Namespace: eosc.NamespaceExtender,
+				Key:       ed.Id,
+				Data:      []byte(ed.Version),
+			})
+		}
+	}
+	events = append(events, oe.workers.commitEvents(clone, ids, changed)...)
+	return http.StatusOK, nil, events, result
+}
+
+// authorize 导入及apply会修改多个profession，按插件及每个操作的profession鉴权
+func (oe *ExportApi) authorize(r *http.Request, extenders []*ImportDiff, ops []*TransactionOperation) *ImportError {
+	for _, ed := range extenders {
+		if err := oe.auth.authorize(r, http.MethodPut, "extender"); err != nil {
+			return &ImportError{Id: ed.Id, Error: err.Error()}
+		}
+	}
+	if i, err := authorizeOperations(oe.auth, r, ops); err != nil {
+		return newImportError(ops[i], err)
+	}
+	return nil
+}
+
+// validateOperations 在副本上依次执行操作，返回每个操作的worker id及结果
+func validateOperations(ws *Workers, ops []*TransactionOperation) ([]string, []*WorkerInfo, *ImportError) {
+	ids := make([]string, 0, len(ops))
+	changed := make([]*WorkerInfo, 0, len(ops))
+	for _, op := range ops {
+		es, worker, err := applyOperation(ws, op)
+		if err != nil {
+			return nil, nil, newImportError(op, err)
+		}
+		ids = append(ids, es[0].Key)
+		changed = append(changed, worker)
+	}
+	return ids, changed, nil
+}
+
+// extenderRequired 导入包中带有插件时，driver不存在的错误提示需要先导入插件
+func extenderRequired(err error, extenders []*ImportDiff) error {
+	if len(extenders) == 0 || !errors.Is(err, eosc.ErrorDriverNotExist) {
+		return err
+	}
+	ids := make([]string, 0, len(extenders))
+	for _, ed := range extenders {
+		ids = append(ids, fmt.Sprintf("%s:%s", ed.Id, ed.Version))
+	}
+	return fmt.Errorf("%w, extenders of the bundle are not loaded, import them first:%s", err, strings.Join(ids, ","))
+}
+
+func newImportError(op *TransactionOperation, err error) *ImportError {
+	id, _ := eosc.ToWorkerId(op.Name, op.Profession)
+	return &ImportError{Id: id, Error: err.Error()}
+}
+
+func (oe *ExportApi) extenderDiff(extenders map[string]string) []*ImportDiff {
+	diffs := make([]*ImportDiff, 0, len(extenders))
+	for id, version := range extenders {
+		group, project := readProject(id)
+		origin, has := oe.extenders.getVersion(group, project)
+		if has && origin == version {
+			continue
+		}
+		action := DiffCreate
+		if has {
+			action = DiffUpdate
+		}
+		diffs = append(diffs, &ImportDiff{Id: id, Action: action, Version: version, Origin: origin})
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Id < diffs[j].Id
+	})
+	return diffs
+}
+
+// workerDiff 对比导入数据与当前数据，返回需要执行的操作（已排序）以及差异列表
+func (oe *ExportApi) workerDiff(items []*TransactionOperation, tenant string, replace bool) ([]*TransactionOperation, []*ImportDiff) {
+	ops := make([]*TransactionOperation, 0, len(items))
+	diffs := make([]*ImportDiff, 0, len(items))
+	imported := make(map[string]bool, len(items))
+	for _, item := range items {
+		id, _ := eosc.ToWorkerId(item.Name, item.Profession)
+		imported[id] = true
+		org, err := oe.workers.GetEmployee(item.Profession, item.Name)
+		if err != nil {
+			ops = append(ops, item)
+			diffs = append(diffs, &ImportDiff{Id: id, Action: DiffCreate})
+			continue
+		}
+		if isSameConfig(org, item.Body) {
+			continue
+		}
+		ops = append(ops, item)
+		diffs = append(diffs, &ImportDiff{Id: id, Action: DiffUpdate})
+	}
+	if !replace {
+		return ops, diffs
+	}
+
+	for _, id := range oe.deleteOrder(tenant, imported) {
+		profession, name, _ := eosc.SplitWorkerId(id)
+		ops = append(ops, &TransactionOperation{Action: TransactionDelete, Profession: profession, Name: name})
+		diffs = append(diffs, &ImportDiff{Id: id, Action: DiffDelete})
+	}
+	return ops, diffs
+}
+
+// deleteOrder 返回租户下需要删除的worker，引用方排在被引用方前面
+func (oe *ExportApi) deleteOrder(tenant string, keep map[string]bool) []string {
+	ps := oe.profession.Sort()
+	ids := make([]string, 0)
+	for i := len(ps) - 1; i >= 0; i-- {
+		list := make([]string, 0)
+		for _, w := range oe.workers.data.List() {
+			if w.config.Profession == ps[i].Name && w.config.Tenant == tenant && !keep[w.config.Id] {
+				list = append(list, w.config.Id)
+			}
+		}
+		sort.Strings(list)
+		ids = append(ids, list...)
+	}
+	return leafFirst(ids, oe.workers.requireManager)
+}
+
+// leafFirst 对ids排序，保证引用方在被引用方之前
+func leafFirst(ids []string, requireManager eosc.IRequires) []string {
+	rs := make([]string, 0, len(ids))
+	done := make(map[string]bool, len(ids))
+	visiting := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		if done[id] || visiting[id] {
+			return
+		}
+		visiting[id] = true
+		for _, by := range requireManager.RequireBy(id) {
+			visit(by)
+		}
+		visiting[id] = false
+		done[id] = true
+		rs = append(rs, id)
+	}
+	for _, id := range ids {
+		visit(id)
+	}
+	// 只返回需要删除的对象，其他引用方依旧存在时由删除操作返回错误
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	out := rs[:0]
+	for _, id := range rs {
+		if want[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// sortImportWorkers 按照profession的顺序排序，同时保证被引用的worker排在引用它的worker前面
+func sortImportWorkers(ps []*professions.Profession, items []*TransactionOperation) []*TransactionOperation {
+	index := make(map[string]int, len(ps))
+	for i, p := range ps {
+		index[p.Name] = i
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		pi, has := index[items[i].Profession]
+		if !has {
+			pi = len(ps)
+		}
+		pj, has := index[items[j].Profession]
+		if !has {
+			pj = len(ps)
+		}
+		if pi != pj {
+			return pi < pj
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	ids := make(map[string]*TransactionOperation, len(items))
+	for _, item := range items {
+		id, _ := eosc.ToWorkerId(item.Name, item.Profession)
+		ids[id] = item
+	}
+	rs := make([]*TransactionOperation, 0, len(items))
+	done := make(map[*TransactionOperation]bool, len(items))
+	visiting := make(map[*TransactionOperation]bool)
+	var visit func(item *TransactionOperation)
+	visit = func(item *TransactionOperation) {
+		if done[item] || visiting[item] {
+			return
+		}
+		visiting[item] = true
+		for _, ref := range collectStrings(item.Body) {
+			if dep, has := ids[strings.ToLower(ref)]; has && dep != item {
+				visit(dep)
+			}
+		}
+		visiting[item] = false
+		done[item] = true
+		rs = append(rs, item)
+	}
+	for _, item := range items {
+		visit(item)
+	}
+	return rs
+}
+
+func collectStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		rs := make([]string, 0, len(t))
+		for _, i := range t {
+			rs = append(rs, collectStrings(i)...)
+		}
+		return rs
+	case map[string]interface{}:
+		rs := make([]string, 0, len(t))
+		for _, i := range t {
+			rs = append(rs, collectStrings(i)...)
+		}
+		return rs
+	}
+	return nil
+}
+
+var importIgnoreFields = []string{"id", "profession", "create", "update", "version", workerDisableField, workerTenantField}
+
+func isSameConfig(org *WorkerInfo, body map[string]interface{}) bool {
+	current := make(map[string]interface{})
+	json.Unmarshal(org.config.Body, &current)
+	current["name"] = org.config.Name
+	current["driver"] = org.config.Driver
+	current["description"] = org.config.Description
+
+	target := make(map[string]interface{}, len(body))
+	for k, v := range body {
+		target[k] = v
+	}
+	if _, has := target["description"]; !has {
+		target["description"] = ""
+	}
+	for _, k := range importIgnoreFields {
+		delete(current, k)
+		delete(target, k)
+	}
+	// 统一经过json编码，避免数字类型不一致
+	cd, _ := json.Marshal(current)
+	td, _ := json.Marshal(target)
+	cm := make(map[string]interface{})
+	tm := make(map[string]interface{})
+	json.Unmarshal(cd, &cm)
+	json.Unmarshal(td, &tm)
+	return reflect.DeepEqual(cm, tm)
+}
+
+// readImportBundle 读取zip包或yaml，zip包中每个文件的结构与yaml相同
+func readImportBundle(data []byte) (*importBundle, error) {
+	values := make(map[string][]map[string]interface{})
+	if bytes.HasPrefix(data, zipMagic) {
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range reader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			if err := mergeImportValues(values, content); err != nil {
+				return nil, fmt.Errorf("%s:%w", file.Name, err)
+			}
+		}
+	} else {
+		if err := mergeImportValues(values, data); err != nil {
+			return nil, err
+		}
+	}
+
+	bundle := &importBundle{extenders: make(map[string]string)}
+	for key, list := range values {
+		switch key {
+		case importKeyExtenders:
+			for _, item := range list {
+				id, _ := item["id"].(string)
+				version, _ := item["version"].(string)
+				bundle.extenders[id] = version
+			}
+			continue
+		case importKeyProfessions, Setting:
+			// profession 由配置文件决定，导入时忽略
+			continue
+		}
+		for _, item := range list {
+			name, _ := item["name"].(string)
+			if name == "" {
+				return nil, fmt.Errorf("%s:require name", key)
+			}
+			body := make(map[string]interface{}, len(item))
+			for k, v := range item {
+				body[k] = v
+			}
+			for _, k := range importIgnoreFields {
+				delete(body, k)
+			}
+			bundle.workers = append(bundle.workers, &TransactionOperation{
+				Action:     TransactionUpdate,
+				Profession: strings.ToLower(key),
+				Name:       name,
+				Body:       body,
+			})
+		}
+	}
+	return bundle, nil
+}
+
+func mergeImportValues(values map[string][]map[string]interface{}, content []byte) error {
+	// extenders 的格式为 group:project:version 字符串列表，单独处理
+	raw := make(map[string]json.RawMessage)
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return err
+	}
+	for key, v := range raw {
+		if key == importKeyExtenders {
+			continue
+		}
+		list := make([]map[string]interface{}, 0)
+		if err := json.Unmarshal(v, &list); err != nil {
+			log.Warn("import: skip ", key, ":", err)
+			continue
+		}
+		values[key] = append(values[key], list...)
+	}
+	if v, has := raw[importKeyExtenders]; has {
+		list := make([]string, 0)
+		if err := json.Unmarshal(v, &list); err != nil {
+			return fmt.Errorf("%s:%w", importKeyExtenders, err)
+		}
+		for _, e := range list {
+			i := strings.LastIndex(e, ":")
+			if i < 0 {
+				return fmt.Errorf("invalid extender:%s", e)
+			}
+			values[importKeyExtenders] = append(values[importKeyExtenders], map[string]interface{}{"id": e[:i], "version": e[i+1:]})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package process_admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportApi_importData(t *testing.T) {
+	ws := newTestWorkers(t, nil)
+	api := NewExportApi(NewExtenderData(nil, nil), ws.professions, ws, nil)
+	if status, _ := doTransaction(NewTransactionApi(ws, nil), `{"operations":[
+		{"action":"create","profession":"router","name":"a","body":{"driver":"http","value":"a"}}]}`); status != http.StatusOK {
+		t.Fatalf("create status = %d", status)
+	}
+	doImport := func(query, data string) (int, interface{}, int) {
+		r := httptest.NewRequest(http.MethodPost, "/import?"+query, strings.NewReader(data))
+		status, _, events, body := api.importData(r, nil)
+		if status != http.StatusOK && events != nil {
+			t.Errorf("failed import should not return events: %v", events)
+		}
+		return status, body, len(events)
+	}
+
+	status, body, _ := doImport("dry_run=true", `router:
+- {name: a, driver: http, value: a}
+- {name: b, driver: http, value: b}
+- {name: c, driver: grpc, value: c}
+`)
+	if status != http.StatusOK {
+		t.Fatalf("dry run status = %d, %v", status, body)
+	}
+	diffs := body.(*ImportResult).Workers
+	if len(diffs) != 2 || diffs[0].Id != "b@router" || diffs[0].Action != DiffCreate || diffs[0].Error != "" {
+		t.Fatalf("diff = %v", diffs)
+	}
+	if diffs[1].Id != "c@router" || !strings.Contains(diffs[1].Error, "driver not exist") {
+		t.Errorf("unresolved driver should be reported in diff, got %v", diffs[1])
+	}
+
+	status, body, _ = doImport("mode=replace", `router:
+- {name: b, driver: http, value: b}
+- {name: c, driver: http, value: invalid}
+`)
+	if status != http.StatusBadRequest || body.(*ImportError).Id != "c@router" {
+		t.Fatalf("invalid import: status = %d, %v", status, body)
+	}
+	if _, has := ws.data.GetInfo("b@router"); has {
+		t.Fatal("failed import should not change workers")
+	}
+
+	status, body, count := doImport("mode=replace", `router:
+- {name: b, driver: http, value: b}
+`)
+	if status != http.StatusOK || count == 0 {
+		t.Fatalf("import status = %d, %v", status, body)
+	}
+	if _, has := ws.data.GetInfo("a@router"); has {
+		t.Error("a@router should be deleted in replace mode")
+	}
+	if _, has := ws.data.GetInfo("b@router"); !has {
+		t.Error("b@router not imported")
+	}
+
+	// 租户下导入，replace只删除租户下的worker
+	r := httptest.NewRequest(http.MethodPost, "/import?mode=replace", strings.NewReader(`router:
+- {name: c, driver: http, value: c}
+`))
+	r.Header.Set(HeaderTenant, "acme")
+	if status, _, _, body := api.importData(r, nil); status != http.StatusOK {
+		t.Fatalf("tenant import status = %d, %v", status, body)
+	}
+	if _, has := ws.data.GetInfo("acme/c@router"); !has {
+		t.Error("acme/c@router not imported")
+	}
+	if _, has := ws.data.GetInfo("b@router"); !has {
+		t.Error("workers of default tenant should not be deleted by tenant import")
+	}
+}
@@ -9,8 +9,6 @@ import (
 	"github.com/eolinker/eosc/setting"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
-	"reflect"
-	"strings"
 )
 
 type SettingApi struct {
@@ -55,114 +53,106 @@ func (oe *SettingApi) Set(req *http.Request, params httprouter.Params) (status i
 	}
 	configType := driver.ConfigType()
 	if driver.Mode() == eosc.SettingModeSingleton {
+		if isDryRun(req) {
+			// 只校验配置，不修改setting
+			if _, _, err := oe.variable.Unmarshal(inputData, configType); err != nil {
+				return http.StatusBadRequest, nil, nil, err.Error()
+			}
+			return http.StatusOK, nil, nil, setting.FormatConfig(inputData, configType)
+		}
 		err := oe.settings.SettingWorker(name, inputData, oe.variable)
 		if err != nil {
 			return http.StatusServiceUnavailable, nil, nil, err.Error()
 		}
-		wc := &eosc.WorkerConfig{
-			Id:          fmt.Sprintf("%s@setting", name),
-			Profession:  Setting,
-			Name:        name,
-			Driver:      name,
-			Create:      eosc.Now(),
-			Update:      eosc.Now(),
-			Body:        inputData,
-			Description: "",
-		}
-		eventData, _ := json.Marshal(wc)
-		return http.StatusOK, nil, []*open_api.EventResponse{{
-			Event:     eosc.EventSet,
-			Namespace: eosc.NamespaceWorker,
-			Key:       wc.Id,
-			Data:      eventData,
-		}}, setting.FormatConfig(inputData, configType)
-	} else {
-		events, body, err = oe.batchSet(inputData, driver, configType)
-		if err != nil {
-			status = http.StatusServiceUnavailable
-			body = err.Error()
-			log.Debug("batch set:", name, ":", string(inputData))
-			log.Info("batch set:", name, ":", err)
-			return
-		}
-		status = http.StatusOK
-		return
+		return http.StatusOK, nil, []*open_api.EventResponse{settingEvent(name, inputData)}, setting.FormatConfig(inputData, configType)
 	}
+	return oe.batchSet(req, name, inputData)
 }
 
-func (oe *SettingApi) batchSet(inputData []byte, driver eosc.ISetting, configType reflect.Type) ([]*open_api.EventResponse, interface{}, error) {
-	type BatchWorkerInfo struct {
-		id         string
-		profession string
-		name       string
-		driver     string
-		desc       string
-		configBody []byte
-	}
-	inputList := splitConfig(inputData)
-	cfgs := make(map[string]BatchWorkerInfo, len(inputList))
-	allWorkers := toSet(driver.AllWorkers())
-	events := make([]*open_api.EventResponse, 0, len(allWorkers))
-	responseBody := make([]interface{}, 0, len(inputList))
-	for _, inp := range inputList {
-		cfg, _, err2 := oe.variable.Unmarshal(inp, configType)
-		if err2 != nil {
+// settingEvent 保存setting的原始配置，admin重启时由 NewSettingApi 恢复
+func settingEvent(name string, inputData []byte) *open_api.EventResponse {
+	wc := &eosc.WorkerConfig{
+		Id:          fmt.Sprintf("%s@setting", name),
+		Profession:  Setting,
+		Name:        name,
+		Driver:      name,
+		Create:      eosc.Now(),
+		Update:      eosc.Now(),
+		Body:        inputData,
+		Description: "",
+	}
+	eventData, _ := json.Marshal(wc)
+	return &open_api.EventResponse{
+		Event:     eosc.EventSet,
+		Namespace: eosc.NamespaceWorker,
+		Key:       wc.Id,
+		Data:      eventData,
+	}
+}
 
-			return nil, nil, err2
-		}
-		profession, workerName, driverName, desc, errCk := driver.Check(cfg)
-		if errCk != nil {
+// BatchSettingResult 批量配置的结果，列出新建、更新、删除的worker
+type BatchSettingResult struct {
+	Create  []string      `json:"create"`
+	Update  []string      `json:"update"`
+	Delete  []string      `json:"delete"`
+	Workers []interface{} `json:"workers"`
+	DryRun  bool          `json:"dry_run,omitempty"`
+}
 
-			return nil, nil, errCk
-		}
-		id, _ := eosc.ToWorkerId(workerName, profession)
-		if allWorkers[id] {
-			delete(allWorkers, id)
-		}
-		cfgs[id] = BatchWorkerInfo{
-			id:         id,
-			profession: profession,
-			name:       workerName,
-			driver:     driverName,
-			desc:       desc,
-			configBody: inp,
-		}
+// batchSet 批量配置的每一项保存为独立的worker，不在配置中的 AllWorkers 被删除
+// 先在副本上执行所有变更，全部成功后提交副本，并保存原始配置用于重启后恢复
+func (oe *SettingApi) batchSet(req *http.Request, name string, inputData []byte) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	batch, err := oe.settings.Batch(name, inputData, oe.variable)
+	if err != nil {
+		log.Debug("batch set:", name, ":", string(inputData))
+		log.Info("batch set:", name, ":", err)
+		return http.StatusBadRequest, nil, nil, err.Error()
+	}
+	items := append(append(make([]*setting.BatchItem, 0, len(batch.Create)+len(batch.Update)), batch.Create...), batch.Update...)
+	result := &BatchSettingResult{
+		Create:  make([]string, 0, len(batch.Create)),
+		Update:  make([]string, 0, len(batch.Update)),
+		Delete:  batch.Delete,
+		Workers: make([]interface{}, 0, len(items)),
+		DryRun:  isDryRun(req),
+	}
+	for _, item := range batch.Create {
+		result.Create = append(result.Create, item.Id)
 	}
-	idtoDelete := make([]string, 0, len(allWorkers))
-	for id := range allWorkers {
-		idtoDelete = append(idtoDelete, id)
+	for _, item := range batch.Update {
+		result.Update = append(result.Update, item.Id)
+	}
+	if result.Delete == nil {
+		result.Delete = make([]string, 0)
 	}
 
-	cannotDelete := oe.workers.DeleteTest(idtoDelete...)
-	if len(cannotDelete) > 0 {
-		return nil, nil, fmt.Errorf("should not delete:%s", strings.Join(cannotDelete, ","))
-	}
-	for id, cfg := range cfgs {
-		info, errSet := oe.workers.set(id, cfg.profession, cfg.name, cfg.driver, cfg.desc, cfg.configBody)
-		if errSet != nil {
-			log.Warnf("bath set skip %s by error:%v", id, ":", errSet)
-			continue
+	clone := oe.workers.Clone()
+	ids := make([]string, 0, len(items)+len(batch.Delete))
+	changed := make([]*WorkerInfo, 0, len(items)+len(batch.Delete))
+	for _, item := range items {
+		info, err := clone.set(item.Id, item.Profession, item.Name, item.Driver, item.Description, item.Body)
+		if err != nil {
+			return saveError(http.StatusBadRequest, fmt.Errorf("%s:%w", item.Id, err))
 		}
-		configData, _ := json.Marshal(info.config)
-		responseBody = append(responseBody, info.Detail())
-		events = append(events, &open_api.EventResponse{
-			Event:     eosc.EventSet,
-			Namespace: eosc.NamespaceWorker,
-			Key:       id,
-			Data:      configData,
-		})
+		result.Workers = append(result.Workers, info.Detail())
+		ids = append(ids, item.Id)
+		changed = append(changed, info)
+	}
+	for _, id := range batch.Delete {
+		if _, err := clone.Delete(id); err != nil {
+			return http.StatusConflict, nil, nil, fmt.Sprintf("delete %s:%s", id, err)
+		}
+		ids = append(ids, id)
+		changed = append(changed, nil)
+	}
+	if result.DryRun {
+		return http.StatusOK, nil, nil, result
 	}
 
-	for _, id := range idtoDelete {
-		oe.workers.Delete(id)
-		events = append(events, &open_api.EventResponse{
-			Event:     eosc.EventDel,
-			Namespace: eosc.NamespaceWorker,
-			Key:       id,
-			Data:      nil,
-		})
-	}
-	return events, responseBody, nil
+	events = oe.workers.commitEvents(clone, ids, changed)
+	oe.settings.SetBatch(name, inputData, batch, oe.variable)
+	events = append(events, settingEvent(name, inputData))
+	return http.StatusOK, nil, events, result
 }
 
 func (oe *SettingApi) Get(req *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
@@ -199,11 +189,3 @@ func NewSettingApi(init map[string][]byte, workers *Workers, variable eosc.IVari
 		settings: datas,
 	}
 }
-
-func toSet(ids []string) map[string]bool {
-	s := make(map[string]bool)
-	for _, id := range ids {
-		s[id] = true
-	}
-	return s
-}
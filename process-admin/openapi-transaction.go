@@ -0,0 +1,224 @@
+package process_admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eolinker/eosc"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/eolinker/eosc/utils/schema"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	TransactionCreate = "create"
+	TransactionUpdate = "update"
+	TransactionPatch  = "patch"
+	TransactionDelete = "delete"
+)
+
+// TransactionOperation 事务中的单个操作
+type TransactionOperation struct {
+	Action     string                 `json:"action" yaml:"action"`
+	Profession string                 `json:"profession" yaml:"profession"`
+	Name       string                 `json:"name" yaml:"name"`
+	Body       map[string]interface{} `json:"body,omitempty" yaml:"body"`
+}
+
+type TransactionRequest struct {
+	Operations []*TransactionOperation `json:"operations" yaml:"operations"`
+}
+
+// TransactionResult 事务中单个操作的执行结果
+type TransactionResult struct {
+	Index  int         `json:"index"`
+	Action string      `json:"action"`
+	Id     string      `json:"id"`
+	Worker interface{} `json:"worker,omitempty"`
+}
+
+// TransactionError 事务校验失败时返回，index为失败操作在请求中的下标
+type TransactionError struct {
+	Index  int                    `json:"index"`
+	Error  string                 `json:"error"`
+	Fields schema.ValidationError `json:"fields,omitempty"`
+}
+
+func newTransactionError(index int, err error) *TransactionError {
+	te := &TransactionError{Index: index, Error: err.Error()}
+	errors.As(err, &te.Fields)
+	return te
+}
+
+type TransactionApi struct {
+	workers *Workers
+	auth    *AuthApi
+}
+
+func NewTransactionApi(workers *Workers, auth *AuthApi) *TransactionApi {
+	return &TransactionApi{workers: workers, auth: auth}
+}
+
+func (oe *TransactionApi) Register(router *httprouter.Router) {
+	router.POST("/transaction", open_api.CreateHandleFunc(oe.transaction))
+	router.POST("/batch", open_api.CreateHandleFunc(oe.transaction))
+}
+
+// transaction 先在副本上执行全部操作，全部通过后用副本替换当前数据，产生的事件由master在同一个事务中提交
+func (oe *TransactionApi) transaction(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	decoder, err := GetData(r)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	req := new(TransactionRequest)
+	err = decoder.UnMarshal(req)
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	if len(req.Operations) == 0 {
+		return http.StatusBadRequest, nil, nil, "nothing to do"
+	}
+
+	if i, err := authorizeOperations(oe.auth, r, req.Operations); err != nil {
+		return http.StatusForbidden, nil, nil, newTransactionError(i, err)
+	}
+	tenant := tenantOf(r)
+	for _, op := range req.Operations {
+		scopeOperation(tenant, op)
+	}
+
+	clone := oe.workers.Clone()
+	results := make([]*TransactionResult, 0, len(req.Operations))
+	ids := make([]string, 0, len(req.Operations))
+	changed := make([]*WorkerInfo, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		es, worker, err := applyOperation(clone, op)
+		if err != nil {
+			return http.StatusBadRequest, nil, nil, newTransactionError(i, err)
+		}
+		result := &TransactionResult{Index: i, Action: op.Action, Id: es[0].Key}
+		if worker != nil {
+			result.Worker = worker.Detail()
+		}
+		results = append(results, result)
+		ids = append(ids, result.Id)
+		changed = append(changed, worker)
+	}
+
+	// 全部通过后直接使用副本的结果，事件按操作顺序生成
+	return http.StatusOK, nil, oe.workers.commitEvents(clone, ids, changed), results
+}
+
+// authorizeOperations 按每个操作的profession鉴权，返回第一个没有权限的操作下标
+func authorizeOperations(a *AuthApi, r *http.Request, ops []*TransactionOperation) (int, error) {
+	for i, op := range ops {
+		if op == nil {
+			continue
+		}
+		if err := a.authorize(r, operationMethod(op.Action), strings.ToLower(op.Profession)); err != nil {
+			return i, err
+		}
+	}
+	return 0, nil
+}
+
+// operationMethod 返回操作对应的HTTP方法
+func operationMethod(action string) string {
+	switch strings.ToLower(action) {
+	case TransactionCreate:
+		return http.MethodPost
+	case TransactionPatch:
+		return http.MethodPatch
+	case TransactionDelete:
+		return http.MethodDelete
+	}
+	return http.MethodPut
+}
+
+// scopeOperation 将操作的worker名称限定在租户下
+func scopeOperation(tenant string, op *TransactionOperation) {
+	if op == nil || tenant == "" {
+		return
+	}
+	name := op.Name
+	if name == "" {
+		name, _ = op.Body["name"].(string)
+	}
+	if name != "" {
+		op.Name = scopedName(tenant, name)
+	}
+}
+
+func applyOperation(ws *Workers, op *TransactionOperation) ([]*open_api.EventResponse, *WorkerInfo, error) {
+	if op == nil {
+		return nil, nil, fmt.Errorf("empty operation")
+	}
+	profession := strings.ToLower(op.Profession)
+	if profession == Setting {
+		return nil, nil, fmt.Errorf("profession %s not support in transaction", op.Profession)
+	}
+	name := op.Name
+	if name == "" {
+		if v, ok := op.Body["name"].(string); ok {
+			name = v
+		}
+	}
+	if name == "" {
+		return nil, nil, fmt.Errorf("require name")
+	}
+
+	switch strings.ToLower(op.Action) {
+	case TransactionCreate, TransactionUpdate:
+		if op.Body == nil {
+			op.Body = make(map[string]interface{})
+		}
+		_, op.Body["name"] = eosc.SplitTenant(name)
+		data, err := json.Marshal(op.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		cb := new(BaseArg)
+		if err := json.Unmarshal(data, cb); err != nil {
+			return nil, nil, err
+		}
+		if strings.ToLower(op.Action) == TransactionCreate {
+			if _, err := ws.GetEmployee(profession, name); err == nil {
+				return nil, nil, fmt.Errorf("%s@%s:%w", name, profession, ErrorExist)
+			}
+		}
+		obj, err := ws.Update(profession, name, cb.Driver, cb.Description, JsonData(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		return ws.setEvents(obj), obj, nil
+	case TransactionPatch:
+		if len(op.Body) == 0 {
+			return nil, nil, fmt.Errorf("nothing to patch")
+		}
+		obj, err := ws.Patch(profession, name, op.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ws.setEvents(obj), obj, nil
+	case TransactionDelete:
+		id, ok := eosc.ToWorkerId(name, profession)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid name:%s for %s", name, profession)
+		}
+		p, has := ws.professions.Get(profession)
+		if !has {
+			return nil, nil, fmt.Errorf("invalid profession:%s", profession)
+		}
+		if p.Mod == eosc.ProfessionConfig_Singleton {
+			return nil, nil, fmt.Errorf("not allow delete %s for %s", name, profession)
+		}
+		if _, err := ws.Delete(id); err != nil {
+			return nil, nil, fmt.Errorf("%s:%w", id, err)
+		}
+		return ws.delEvents(id), nil, nil
+	}
+	return nil, nil, fmt.Errorf("unknown action:%s", op.Action)
+}
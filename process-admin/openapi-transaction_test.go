@@ -0,0 +1,125 @@
+package process_admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/auth"
+	open_api "github.com/eolinker/eosc/open-api"
+)
+
+func doTransaction(api *TransactionApi, body string) (int, []*open_api.EventResponse) {
+	r := httptest.NewRequest(http.MethodPost, "/transaction", strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	status, _, events, _ := api.transaction(r, nil)
+	return status, events
+}
+
+func TestTransactionApi_transaction(t *testing.T) {
+	ws := newTestWorkers(t, nil)
+	api := NewTransactionApi(ws, nil)
+
+	status, events := doTransaction(api, `{"operations":[
+		{"action":"create","profession":"router","name":"a","body":{"driver":"http","value":"a"}},
+		{"action":"create","profession":"router","name":"b","body":{"driver":"http","value":"invalid"}}]}`)
+	if status != http.StatusBadRequest || events != nil {
+		t.Fatalf("failed transaction: status = %d, events = %v", status, events)
+	}
+	if _, has := ws.data.GetInfo("a@router"); has {
+		t.Fatal("failed transaction should not change workers")
+	}
+
+	status, events = doTransaction(api, `{"operations":[
+		{"action":"create","profession":"router","name":"a","body":{"driver":"http","value":"a"}},
+		{"action":"create","profession":"router","name":"b","body":{"driver":"http","value":"b"}}]}`)
+	if status != http.StatusOK || len(events) == 0 {
+		t.Fatalf("status = %d, events = %v", status, events)
+	}
+
+	// 当前实例Reset会失败，提交副本后使用副本中新建的实例，原实例被销毁
+	before, _ := ws.data.GetInfo("a@router")
+	removed, _ := ws.data.GetInfo("b@router")
+	status, _ = doTransaction(api, `{"operations":[
+		{"action":"update","profession":"router","name":"a","body":{"driver":"http","value":"reset-fail"}},
+		{"action":"delete","profession":"router","name":"b"}]}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	info, has := ws.data.GetInfo("a@router")
+	if !has || info.worker.(*testWorker).value != "reset-fail" {
+		t.Errorf("a@router not committed")
+	}
+	if _, has := ws.data.GetInfo("b@router"); has {
+		t.Errorf("b@router not deleted")
+	}
+	if info.worker == before.worker || !before.worker.(*testWorker).destroyed {
+		t.Errorf("replaced instance of a@router not destroyed")
+	}
+	if !removed.worker.(*testWorker).destroyed {
+		t.Errorf("instance of b@router not destroyed")
+	}
+	var iw eosc.IWorkers = ws.data
+	if _, has := iw.Get("a@router"); !has {
+		t.Errorf("injected workers not updated")
+	}
+}
+
+func TestTransactionApi_authorize(t *testing.T) {
+	ws := newTestWorkers(t, nil)
+	api := NewTransactionApi(ws, NewAuthApi(map[string][]byte{
+		auth.UserKey("operator"):     []byte(`{"name":"operator","roles":["write-router"]}`),
+		auth.RoleKey("write-router"): []byte(`{"name":"write-router","rules":[{"resources":["router","transaction"],"methods":["write"]}]}`),
+	}))
+	doAs := func(user, body string) (int, *TransactionError) {
+		r := httptest.NewRequest(http.MethodPost, "/transaction", strings.NewReader(body))
+		r.Header.Set("content-type", "application/json")
+		r.Header.Set(auth.HeaderPrincipal, user)
+		status, _, _, res := api.transaction(r, nil)
+		te, _ := res.(*TransactionError)
+		return status, te
+	}
+
+	// 可以写transaction不代表可以修改其他profession
+	status, te := doAs("operator", `{"operations":[
+		{"action":"create","profession":"router","name":"a","body":{"driver":"http","value":"a"}},
+		{"action":"update","profession":"setting","name":"plugin","body":{}}]}`)
+	if status != http.StatusForbidden || te == nil || te.Index != 1 {
+		t.Fatalf("status = %d, %v", status, te)
+	}
+	if _, has := ws.data.GetInfo("a@router"); has {
+		t.Fatal("forbidden transaction should not change workers")
+	}
+	if status, _ = doAs("", `{"operations":[
+		{"action":"create","profession":"router","name":"a","body":{"driver":"http","value":"a"}}]}`); status != http.StatusForbidden {
+		t.Fatalf("unknown user: status = %d", status)
+	}
+	if status, te = doAs("operator", `{"operations":[
+		{"action":"create","profession":"router","name":"a","body":{"driver":"http","value":"a"}}]}`); status != http.StatusOK {
+		t.Fatalf("status = %d, %v", status, te)
+	}
+}
+
+func TestTransactionApi_patch(t *testing.T) {
+	data, _ := json.Marshal(&eosc.WorkerConfig{
+		Id:         "a@router",
+		Profession: "router",
+		Name:       "a",
+		Driver:     "http",
+		Body:       []byte(`{"value":"v1","old":1}`),
+	})
+	api := NewTransactionApi(newTestWorkersWithData(t, nil, map[string][]byte{"a@router": data}), nil)
+
+	// 已保存的未知字段不影响修改其他字段
+	if status, _ := doTransaction(api, `{"operations":[
+		{"action":"patch","profession":"router","name":"a","body":{"value":"v2"}}]}`); status != http.StatusOK {
+		t.Fatalf("patch status = %d", status)
+	}
+	if status, _ := doTransaction(api, `{"operations":[
+		{"action":"patch","profession":"router","name":"a","body":{"other":1}}]}`); status != http.StatusBadRequest {
+		t.Fatalf("patch unknown field: status = %d", status)
+	}
+}
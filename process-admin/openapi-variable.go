@@ -1,8 +1,12 @@
 package process_admin
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/eolinker/eosc"
 	"github.com/eolinker/eosc/log"
@@ -11,6 +15,12 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+// VariableArg 单个变量的值，Secret 为 true 时加密保存
+type VariableArg struct {
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
 type VariableApi struct {
 	extenderData *ExtenderData
 	workers      *Workers
@@ -28,47 +38,63 @@ func (oe *VariableApi) Register(router *httprouter.Router) {
 	router.GET("/variable/:namespace/:key", open_api.CreateHandleFunc(oe.getByKey))
 	router.POST("/variable/:namespace", open_api.CreateHandleFunc(oe.setByNamespace))
 	router.PUT("/variable/:namespace", open_api.CreateHandleFunc(oe.setByNamespace))
+	router.DELETE("/variable/:namespace", open_api.CreateHandleFunc(oe.deleteByNamespace))
+	router.PUT("/variable/:namespace/:key", open_api.CreateHandleFunc(oe.setByKey))
+	router.DELETE("/variable/:namespace/:key", open_api.CreateHandleFunc(oe.deleteByKey))
+	router.GET("/variable/:namespace/:key/usage", open_api.CreateHandleFunc(oe.usage))
 
 }
 
+// getAll 返回当前租户的所有变量，secret变量只返回掩码
 func (oe *VariableApi) getAll(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
-
-	return http.StatusOK, nil, nil, oe.variableData
+	tenant := tenantOf(r)
+	prefix := tenant + eosc.TenantSeparator
+	rs := make(map[string]map[string]string)
+	for namespace, vs := range oe.variableData.All() {
+		t, name := eosc.SplitTenant(namespace)
+		if t != tenant {
+			continue
+		}
+		rs[strings.TrimPrefix(name, prefix)] = variable.MaskSecrets(vs)
+	}
+	return http.StatusOK, nil, nil, rs
 }
 
 func (oe *VariableApi) getByNamespace(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
-	namespace := params.ByName("namespace")
-	if namespace == "" {
-		namespace = "default"
-	}
+	namespace := tenantNamespace(tenantOf(r), params.ByName("namespace"))
 	data, has := oe.variableData.GetByNamespace(namespace)
 	if !has {
 		return http.StatusNotFound, nil, nil, fmt.Sprintf("namespace{%s} not found", namespace)
 	}
-	return http.StatusOK, nil, nil, data
+	return http.StatusOK, nil, nil, variable.MaskSecrets(data)
 }
 
 func (oe *VariableApi) getByKey(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
-	namespace := params.ByName("namespace")
+	namespace := tenantNamespace(tenantOf(r), params.ByName("namespace"))
 	key := params.ByName("key")
-	if namespace == "" {
-		namespace = "default"
-	}
 	data, has := oe.variableData.GetByNamespace(namespace)
 	if !has {
 		return http.StatusNotFound, nil, nil, fmt.Sprintf("namespace{%s} not found", namespace)
 	}
-	value, ok := data[fmt.Sprintf("%s@%s", key, namespace)]
+	value, ok := data[key]
 	if !ok {
 		return http.StatusNotFound, nil, nil, fmt.Sprintf("key{%s} not found", key)
 	}
+	if variable.IsSecret(value) {
+		value = variable.MaskedValue
+	}
 	return http.StatusOK, nil, nil, value
 }
 
+// setByNamespace 租户下的namespace保存为 {tenant}/{namespace}，返回时不带租户前缀
 func (oe *VariableApi) setByNamespace(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
-	namespace := params.ByName("namespace")
-	if namespace == "" {
-		namespace = "default"
+	name := params.ByName("namespace")
+	if name == "" {
+		name = "default"
+	}
+	namespace := tenantNamespace(tenantOf(r), name)
+	if isProviderNamespace(namespace) {
+		return http.StatusBadRequest, nil, nil, fmt.Sprintf("namespace{%s} is provided by node, can not be set", namespace)
 	}
 	decoder, err := GetData(r)
 	if err != nil {
@@ -79,44 +105,67 @@ func (oe *VariableApi) setByNamespace(r *http.Request, params httprouter.Params)
 	if errUnmarshal != nil {
 		return http.StatusInternalServerError, nil, nil, errUnmarshal
 	}
+	old, _ := oe.variableData.GetByNamespace(namespace)
+	if err := sealSecrets(old, cb, readSecretKeys(r)); err != nil {
+		return http.StatusBadRequest, nil, nil, err.Error()
+	}
+	status, events, body = oe.save(namespace, cb, false)
+	if status != http.StatusOK {
+		return status, nil, nil, body
+	}
+	return http.StatusOK, nil, events, map[string]interface{}{
+		"namespace": name,
+		"variables": variable.MaskSecrets(cb),
+	}
+}
+
+// save 保存namespace的变量并重新生成受影响的配置
+// force 为 true 时允许删除被引用的变量，引用的worker保持当前配置
+func (oe *VariableApi) save(namespace string, variables map[string]string, force bool) (int, []*open_api.EventResponse, interface{}) {
 	log.Debug("check variable...")
-	affectIds, clone, err := oe.variableData.Check(namespace, cb)
-	if err != nil {
-		return http.StatusInternalServerError, nil, nil, fmt.Sprintf("namespace{%s} not found", namespace)
+	affectIds, clone, err := oe.variableData.Check(namespace, variables)
+	if err != nil && !(force && errors.Is(err, variable.ErrorVariableRequire)) {
+		if errors.Is(err, variable.ErrorVariableRequire) {
+			return http.StatusConflict, nil, err.Error()
+		}
+		return http.StatusInternalServerError, nil, err.Error()
 	}
 	log.Debug("parse variable...")
-	parse := variable.NewParse(clone)
 	workerToUpdate := make([]CacheItem, 0, len(affectIds))
-	for _, id := range affectIds {
-		profession, name, success := eosc.SplitWorkerId(id)
-		if !success {
-			continue
-		}
-		if profession != Setting {
-			info, err := oe.workers.GetEmployee(profession, name)
-			if err != nil {
-				return http.StatusInternalServerError, nil, nil, fmt.Sprintf("worker(%s) not found, error is %s", id, err)
-			}
-			_, _, err = parse.Unmarshal(info.Body(), info.configType)
-			if err != nil {
-				return http.StatusInternalServerError, nil, nil, fmt.Sprintf("unmarshal error:%s,body is '%s'", err, string(info.Body()))
+	if clone != nil {
+		parse := variable.NewParse(clone)
+		for _, id := range affectIds {
+			profession, name, success := eosc.SplitWorkerId(id)
+			if !success {
+				continue
 			}
-			workerToUpdate = append(workerToUpdate, CacheItem{
-				id:         id,
-				profession: profession,
-			})
-		} else {
-			err := oe.setting.CheckVariable(name, clone)
-			if err != nil {
-				return http.StatusInternalServerError, nil, nil, fmt.Sprintf("setting %s unmarshal error:%s", name, err)
+			if profession != Setting {
+				info, err := oe.workers.GetEmployee(profession, name)
+				if err != nil {
+					return http.StatusInternalServerError, nil, fmt.Sprintf("worker(%s) not found, error is %s", id, err)
+				}
+				_, _, err = parse.Unmarshal(info.Body(), info.configType)
+				if err != nil {
+					return http.StatusInternalServerError, nil, fmt.Sprintf("unmarshal error:%s,body is '%s'", err, string(info.Body()))
+				}
+				workerToUpdate = append(workerToUpdate, CacheItem{
+					id:         id,
+					profession: profession,
+				})
+			} else {
+				err := oe.setting.CheckVariable(name, clone)
+				if err != nil {
+					return http.StatusInternalServerError, nil, fmt.Sprintf("setting %s unmarshal error:%s", name, err)
+				}
+				workerToUpdate = append(workerToUpdate, CacheItem{
+					id:         name,
+					profession: Setting,
+				})
 			}
-			workerToUpdate = append(workerToUpdate, CacheItem{
-				id:         name,
-				profession: Setting,
-			})
 		}
-
 	}
+	log.Debug("set variable...")
+	oe.variableData.SetByNamespace(namespace, variables)
 	log.Debug("update variable...")
 	for _, w := range workerToUpdate {
 		if w.profession != Setting {
@@ -125,19 +174,142 @@ func (oe *VariableApi) setByNamespace(r *http.Request, params httprouter.Params)
 			oe.setting.Update(w.id, oe.variableData)
 		}
 	}
-	log.Debug("set variable...")
-	oe.variableData.SetByNamespace(namespace, cb)
-	log.Debug("set variable over...")
-
-	data, _ := decoder.Encode()
-	return http.StatusOK, nil, []*open_api.EventResponse{{
-			Event:     "set",
-			Namespace: "variable",
+	if len(variables) == 0 {
+		return http.StatusOK, []*open_api.EventResponse{{
+			Event:     eosc.EventDel,
+			Namespace: eosc.NamespaceVariable,
 			Key:       namespace,
-			Data:      data,
-		},
-		}, map[string]interface{}{
-			"namespace": namespace,
-			"variables": cb,
+		}}, nil
+	}
+	data, _ := json.Marshal(variables)
+	return http.StatusOK, []*open_api.EventResponse{{
+		Event:     eosc.EventSet,
+		Namespace: eosc.NamespaceVariable,
+		Key:       namespace,
+		Data:      data,
+	}}, nil
+}
+
+// setByKey 修改单个变量，body为 {"value":"...","secret":true}
+func (oe *VariableApi) setByKey(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	namespace := tenantNamespace(tenantOf(r), params.ByName("namespace"))
+	key := params.ByName("key")
+	if isProviderNamespace(namespace) {
+		return http.StatusBadRequest, nil, nil, fmt.Sprintf("namespace{%s} is provided by node, can not be set", namespace)
+	}
+	decoder, err := GetData(r)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	arg := new(VariableArg)
+	if err := decoder.UnMarshal(arg); err != nil {
+		return http.StatusBadRequest, nil, nil, err.Error()
+	}
+	old, _ := oe.variableData.GetByNamespace(namespace)
+	variables := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		variables[k] = v
+	}
+	value := map[string]string{key: arg.Value}
+	if err := sealSecrets(old, value, map[string]bool{key: arg.Secret}); err != nil {
+		return http.StatusBadRequest, nil, nil, err.Error()
+	}
+	variables[key] = value[key]
+	status, events, body = oe.save(namespace, variables, false)
+	if status != http.StatusOK {
+		return status, nil, nil, body
+	}
+	return http.StatusOK, nil, events, &VariableArg{
+		Value:  variable.MaskSecrets(value)[key],
+		Secret: variable.IsSecret(value[key]),
+	}
+}
+
+// deleteByKey 删除单个变量，变量被引用时需要 force=true
+func (oe *VariableApi) deleteByKey(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	namespace := tenantNamespace(tenantOf(r), params.ByName("namespace"))
+	key := params.ByName("key")
+	old, has := oe.variableData.GetByNamespace(namespace)
+	if !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("namespace{%s} not found", namespace)
+	}
+	if _, ok := old[key]; !ok {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("key{%s} not found", key)
+	}
+	delete(old, key)
+	status, events, body = oe.save(namespace, old, r.URL.Query().Get("force") == "true")
+	if status != http.StatusOK {
+		return status, nil, nil, body
+	}
+	return http.StatusOK, nil, events, nil
+}
+
+// deleteByNamespace 删除namespace下的所有变量，变量被引用时需要 force=true
+func (oe *VariableApi) deleteByNamespace(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	namespace := tenantNamespace(tenantOf(r), params.ByName("namespace"))
+	if _, has := oe.variableData.GetByNamespace(namespace); !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("namespace{%s} not found", namespace)
+	}
+	status, events, body = oe.save(namespace, nil, r.URL.Query().Get("force") == "true")
+	if status != http.StatusOK {
+		return status, nil, nil, body
+	}
+	return http.StatusOK, nil, events, nil
+}
+
+// usage 返回引用了变量的worker
+func (oe *VariableApi) usage(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	tenant := tenantOf(r)
+	namespace := tenantNamespace(tenant, params.ByName("namespace"))
+	id := variable.VariableId(params.ByName("key"), namespace)
+	ids := oe.variableData.GetIdsByVariable(id)
+	workers := make([]string, 0, len(ids))
+	for _, wid := range ids {
+		workers = append(workers, relativeRequire(tenant, wid))
+	}
+	sort.Strings(workers)
+	return http.StatusOK, nil, nil, map[string]interface{}{
+		"variable": params.ByName("key"),
+		"workers":  workers,
+	}
+}
+
+// readSecretKeys 读取需要加密保存的变量，如 ?secret=password,api_key
+func readSecretKeys(r *http.Request) map[string]bool {
+	keys := make(map[string]bool)
+	for _, v := range r.URL.Query()["secret"] {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys[k] = true
+			}
+		}
+	}
+	return keys
+}
+
+// sealSecrets 加密需要保存为secret的变量，已经是secret的变量更新后仍然加密保存，传入掩码时保留原有的值
+func sealSecrets(old, variables map[string]string, secrets map[string]bool) error {
+	for key, value := range variables {
+		oldValue := old[key]
+		if value == variable.MaskedValue && variable.IsSecret(oldValue) {
+			variables[key] = oldValue
+			continue
+		}
+		if variable.IsSecret(value) {
+			// 直接传入的密文需要可以被当前节点解密
+			if _, err := variable.DecryptSecret(value); err != nil {
+				return fmt.Errorf("variable %s:%w", key, err)
+			}
+			continue
 		}
+		if !secrets[key] && !variable.IsSecret(oldValue) {
+			continue
+		}
+		encrypted, err := variable.EncryptSecret(value)
+		if err != nil {
+			return fmt.Errorf("encrypt variable %s:%w", key, err)
+		}
+		variables[key] = encrypted
+	}
+	return nil
 }
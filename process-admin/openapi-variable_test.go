@@ -0,0 +1,43 @@
+package process_admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/variable"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestVariableApi_deleteByKey(t *testing.T) {
+	data, _ := json.Marshal(map[string]string{"host": "example.com", "port": "80"})
+	vs := variable.NewVariables(map[string][]byte{"default": data})
+	ws := newTestWorkers(t, vs)
+	if status, _ := doTransaction(NewTransactionApi(ws, nil), `{"operations":[
+		{"action":"create","profession":"router","name":"a","body":{"driver":"http","value":"${host@default}"}}]}`); status != http.StatusOK {
+		t.Fatalf("create status = %d", status)
+	}
+	api := NewVariableApi(nil, ws, vs, nil)
+	params := httprouter.Params{{Key: "namespace", Value: "default"}, {Key: "key", Value: "host"}}
+
+	status, _, events, body := api.deleteByKey(httptest.NewRequest(http.MethodDelete, "/variable/default/host", nil), params)
+	if status != http.StatusConflict || events != nil {
+		t.Fatalf("delete used variable: status = %d, events = %v, body = %v", status, events, body)
+	}
+	if v, _ := vs.Get("host@default"); v != "example.com" {
+		t.Fatal("variable should not be deleted")
+	}
+
+	status, _, events, body = api.deleteByKey(httptest.NewRequest(http.MethodDelete, "/variable/default/host?force=true", nil), params)
+	if status != http.StatusOK || len(events) != 1 || events[0].Event != eosc.EventSet {
+		t.Fatalf("force delete: status = %d, events = %v, body = %v", status, events, body)
+	}
+	if _, has := vs.Get("host@default"); has {
+		t.Error("variable should be deleted")
+	}
+	if _, has := ws.data.GetInfo("a@router"); !has {
+		t.Error("worker using the variable should keep its config")
+	}
+}
@@ -0,0 +1,115 @@
+package process_admin
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/eolinker/eosc/utils/labels"
+	"github.com/julienschmidt/httprouter"
+)
+
+// BulkPatchResult 批量修改的结果
+type BulkPatchResult struct {
+	Selector string        `json:"selector"`
+	Workers  []interface{} `json:"workers"`
+	DryRun   bool          `json:"dry_run"`
+}
+
+// selectWorkers 读取selector参数并返回匹配的worker，为避免误操作selector不能为空
+func (oe *WorkerApi) selectWorkers(r *http.Request, profession string) (labels.Selector, []*WorkerInfo, error) {
+	if strings.ToLower(profession) == Setting {
+		return nil, nil, fmt.Errorf("profession %s not support selector", profession)
+	}
+	p, has := oe.workers.professions.Get(profession)
+	if !has {
+		return nil, nil, fmt.Errorf("invalid profession:%s", profession)
+	}
+	selector, err := labels.Parse(r.URL.Query().Get("selector"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if selector.Empty() {
+		return nil, nil, fmt.Errorf("require selector")
+	}
+	return selector, oe.workers.Select(tenantOf(r), p.Name, selector), nil
+}
+
+// bulkPatch PATCH /api/:profession?selector=...
+// 对标签匹配的所有worker执行相同的patch，所有事件在同一个批次中提交
+func (oe *WorkerApi) bulkPatch(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	selector, list, err := oe.selectWorkers(r, params.ByName("profession"))
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	decoder, err := GetData(r)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	options := make(map[string]interface{})
+	if err := decoder.UnMarshal(&options); err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	if len(options) == 0 {
+		return http.StatusBadRequest, nil, nil, "nothing to patch"
+	}
+	result := &BulkPatchResult{
+		Selector: selector.String(),
+		Workers:  make([]interface{}, 0, len(list)),
+		DryRun:   isDryRun(r),
+	}
+
+	// 先在副本上执行，确认所有worker都可以修改
+	clone := oe.workers.Clone()
+	for _, w := range list {
+		obj, err := clone.Patch(w.config.Profession, w.TenantName(), options)
+		if err != nil {
+			return saveError(http.StatusBadRequest, fmt.Errorf("%s:%w", w.config.Id, err))
+		}
+		if result.DryRun {
+			result.Workers = append(result.Workers, obj.Detail())
+		}
+	}
+	if result.DryRun {
+		return http.StatusOK, nil, nil, result
+	}
+
+	events = make([]*open_api.EventResponse, 0, len(list))
+	for _, w := range list {
+		obj, err := oe.workers.Patch(w.config.Profession, w.TenantName(), options)
+		if err != nil {
+			return saveError(http.StatusInternalServerError, fmt.Errorf("%s:%w", w.config.Id, err))
+		}
+		events = append(events, oe.workers.setEvents(obj)...)
+		result.Workers = append(result.Workers, obj.Detail())
+	}
+	return http.StatusOK, nil, events, result
+}
+
+// bulkDelete DELETE /api/:profession?selector=...
+// 删除标签匹配的所有worker，cascade=true 时同时删除引用它们的worker
+func (oe *WorkerApi) bulkDelete(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	selector, list, err := oe.selectWorkers(r, params.ByName("profession"))
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	cascade := r.URL.Query().Get("cascade") == "true"
+	ids := make([]string, 0, len(list))
+	visited := make(map[string]bool)
+	for _, w := range list {
+		targets := []string{w.config.Id}
+		if cascade {
+			targets = oe.workers.RequireByClosure(w.config.Id)
+		}
+		for _, id := range targets {
+			if !visited[id] {
+				visited[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return oe.deleteInOrder(tenantOf(r), &CascadeDeleteResult{Selector: selector.String(), DryRun: isDryRun(r)}, ids)
+}
@@ -0,0 +1,62 @@
+package process_admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/eolinker/eosc"
+	open_api "github.com/eolinker/eosc/open-api"
+)
+
+// CascadeDeleteResult 级联删除的预览及结果，order 为删除顺序，引用方在前
+type CascadeDeleteResult struct {
+	Id       string        `json:"id,omitempty"`
+	Selector string        `json:"selector,omitempty"`
+	Order    []string      `json:"order"`
+	Workers  []interface{} `json:"workers"`
+	DryRun   bool          `json:"dry_run"`
+}
+
+// cascadeDelete DELETE /api/:profession/:name?cascade=true
+// 删除worker以及所有直接或间接引用它的worker，dry_run=true 时只返回预览，所有删除事件在同一个批次中提交
+func (oe *WorkerApi) cascadeDelete(tenant, id string, dryRun bool) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	if _, has := oe.workers.data.GetInfo(id); !has {
+		return http.StatusNotFound, nil, nil, fmt.Errorf("%s %w", id, ErrorNotExist)
+	}
+	return oe.deleteInOrder(tenant, &CascadeDeleteResult{Id: id, DryRun: dryRun}, oe.workers.RequireByClosure(id))
+}
+
+// deleteInOrder 按照引用方在前的顺序在副本上删除ids，全部成功后提交副本，所有删除事件在同一个批次中提交
+// 不允许删除其他租户的worker，共享worker被其他租户引用时需要先由该租户解除引用
+func (oe *WorkerApi) deleteInOrder(tenant string, result *CascadeDeleteResult, ids []string) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	dryRun := result.DryRun
+	order := leafFirst(ids, oe.workers.requireManager)
+	result.Order = order
+	result.Workers = make([]interface{}, 0, len(order))
+	for _, wid := range order {
+		w, has := oe.workers.data.GetInfo(wid)
+		if !has {
+			return http.StatusInternalServerError, nil, nil, fmt.Errorf("%s %w", wid, ErrorNotExist)
+		}
+		if w.config.Tenant != tenant {
+			return http.StatusForbidden, nil, nil, fmt.Sprintf("not allow delete %s of other tenant", wid)
+		}
+		p, has := oe.workers.professions.Get(w.config.Profession)
+		if has && p.Mod == eosc.ProfessionConfig_Singleton {
+			return http.StatusForbidden, nil, nil, fmt.Sprintf("not allow delete %s for %s", w.config.Name, w.config.Profession)
+		}
+		result.Workers = append(result.Workers, w.Detail())
+	}
+
+	// 先在副本上按顺序删除，全部成功后提交副本
+	clone := oe.workers.Clone()
+	for _, wid := range order {
+		if _, err := clone.Delete(wid); err != nil {
+			return http.StatusBadRequest, nil, nil, fmt.Errorf("delete %s:%w", wid, err)
+		}
+	}
+	if dryRun {
+		return http.StatusOK, nil, nil, result
+	}
+	return http.StatusOK, nil, oe.workers.commitEvents(clone, order, make([]*WorkerInfo, len(order))), result
+}
@@ -0,0 +1,47 @@
+package process_admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eolinker/eosc"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (oe *WorkerApi) disable(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	return oe.setDisable(r, params, true)
+}
+
+func (oe *WorkerApi) enable(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	return oe.setDisable(r, params, false)
+}
+
+// setDisable 禁用或者启用worker，状态未变化时不产生事件
+func (oe *WorkerApi) setDisable(r *http.Request, params httprouter.Params, disable bool) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	profession := params.ByName("profession")
+	name := tenantName(r, params.ByName("name"))
+	if strings.ToLower(profession) == Setting {
+		return http.StatusForbidden, nil, nil, fmt.Sprintf("not allow disable %s", profession)
+	}
+	p, has := oe.workers.professions.Get(profession)
+	if !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("invalid profession:%s", profession)
+	}
+	if p.Mod == eosc.ProfessionConfig_Singleton {
+		return http.StatusForbidden, nil, nil, fmt.Sprintf("not allow disable %s for %s", name, profession)
+	}
+	org, err := oe.workers.GetEmployee(profession, name)
+	if err != nil {
+		return http.StatusNotFound, nil, nil, err
+	}
+	if org.config.Disable == disable {
+		return http.StatusOK, etagHeader(org), nil, org.Detail()
+	}
+	obj, err := oe.workers.SetDisable(profession, name, disable)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	return http.StatusOK, etagHeader(obj), oe.workers.setEvents(obj), obj.Detail()
+}
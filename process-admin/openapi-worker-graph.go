@@ -0,0 +1,43 @@
+package process_admin
+
+import (
+	"net/http"
+	"strings"
+
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/julienschmidt/httprouter"
+)
+
+const graphDepthDefault = 1
+
+// graph GET /graph 返回租户下所有worker的依赖图
+func (oe *WorkerApi) graph(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	return graphResponse(r, oe.workers.Graph(tenantOf(r)))
+}
+
+// workerGraph GET /api/:profession/:name/graph?depth=N 返回worker相关的依赖图，depth=0 时不限制层数
+func (oe *WorkerApi) workerGraph(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	depth := graphDepthDefault
+	if r.URL.Query().Get("depth") != "" {
+		d, err := readUint(r.URL.Query(), "depth")
+		if err != nil {
+			return http.StatusBadRequest, nil, nil, err
+		}
+		depth = d
+	}
+	g, err := oe.workers.SubGraph(params.ByName("profession"), tenantName(r, params.ByName("name")), depth)
+	if err != nil {
+		return http.StatusNotFound, nil, nil, err
+	}
+	return graphResponse(r, g)
+}
+
+// graphResponse format=dot 或者 Accept 为 text/vnd.graphviz 时返回DOT格式
+func graphResponse(r *http.Request, g *Graph) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	if strings.EqualFold(r.URL.Query().Get("format"), "dot") || strings.Contains(r.Header.Get("Accept"), "text/vnd.graphviz") {
+		header = make(http.Header)
+		header.Set("content-type", "text/vnd.graphviz; charset=utf-8")
+		return http.StatusOK, header, nil, g.DOT()
+	}
+	return http.StatusOK, nil, nil, g
+}
@@ -0,0 +1,67 @@
+package process_admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (oe *WorkerApi) history(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	profession := params.ByName("profession")
+	name := tenantName(r, params.ByName("name"))
+	wInfo, err := oe.workers.GetEmployee(profession, name)
+	if err != nil {
+		return http.StatusNotFound, nil, nil, err
+	}
+	return http.StatusOK, nil, nil, oe.workers.history.List(wInfo.config.Id)
+}
+
+func (oe *WorkerApi) historyRevision(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	revision, _, status, err := oe.getRevision(r, params)
+	if err != nil {
+		return status, nil, nil, err
+	}
+	return http.StatusOK, nil, nil, revision
+}
+
+// rollback 将worker恢复到指定版本，与保存操作一样经过校验并生成事件
+func (oe *WorkerApi) rollback(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	revision, org, status, err := oe.getRevision(r, params)
+	if err != nil {
+		return status, nil, nil, err
+	}
+	// 历史版本保存的是worker id格式的引用，需要转换为租户视角
+	data := JsonData(revision.Body)
+	if org.config.Tenant != "" {
+		current := make(map[string]interface{})
+		json.Unmarshal(revision.Body, &current)
+		data, _ = json.Marshal(relativeBody(org.configType, org.config.Tenant, current))
+	}
+	obj, err := oe.workers.Update(org.config.Profession, org.TenantName(), revision.Driver, revision.Description, data)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	return http.StatusOK, etagHeader(obj), oe.workers.setEvents(obj), obj.Detail()
+}
+
+func (oe *WorkerApi) getRevision(r *http.Request, params httprouter.Params) (*WorkerRevision, *WorkerInfo, int, error) {
+	profession := params.ByName("profession")
+	name := tenantName(r, params.ByName("name"))
+	rev, err := strconv.ParseInt(params.ByName("rev"), 10, 64)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("invalid rev:%s", params.ByName("rev"))
+	}
+	wInfo, err := oe.workers.GetEmployee(profession, name)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, err
+	}
+	revision, has := oe.workers.history.Get(wInfo.config.Id, rev)
+	if !has {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("%s rev %d %w", wInfo.config.Id, rev, ErrorNotExist)
+	}
+	return revision, wInfo, http.StatusOK, nil
+}
@@ -2,10 +2,12 @@ package process_admin
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/eolinker/eosc/log"
 	open_api "github.com/eolinker/eosc/open-api"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -16,14 +18,40 @@ func (oe *WorkerApi) getEmployeesByProfession(r *http.Request, params httprouter
 	if isSkip {
 		return
 	}
-	es, err := oe.workers.ListEmployees(profession)
+	query, err := parseWorkerQuery(r.URL.Query())
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	query.Tenant = tenantOf(r)
+	es, total, err := oe.workers.Query(profession, query)
 	if err != nil {
 		return 500, nil, nil, err
 	}
 
 	out, _ := json.Marshal(es)
 	log.Debug("getEmployeesByProfession:", string(out))
-	return 200, nil, nil, out
+	return 200, totalHeader(total), nil, out
+}
+
+// search 跨profession查询worker，q 匹配名称、描述及AppendLabels字段
+func (oe *WorkerApi) search(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	query, err := parseWorkerQuery(r.URL.Query())
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	query.Tenant = tenantOf(r)
+	es, total, err := oe.workers.Query("", query)
+	if err != nil {
+		return 500, nil, nil, err
+	}
+	return 200, totalHeader(total), nil, es
+}
+
+// totalHeader 分页查询时通过X-Total-Count返回总数
+func totalHeader(total int) http.Header {
+	header := make(http.Header)
+	header.Set("X-Total-Count", strconv.Itoa(total))
+	return header
 }
 
 func (oe *WorkerApi) getEmployeeByName(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
@@ -35,20 +63,32 @@ func (oe *WorkerApi) getEmployeeByName(r *http.Request, params httprouter.Params
 		return
 	}
 
-	name := params.ByName("name")
+	name := tenantName(r, params.ByName("name"))
 	eo, err := oe.workers.GetEmployee(profession, name)
 	if err != nil {
 		return 404, nil, nil, err
 	}
-	return 200, nil, nil, eo.Detail()
+	return 200, etagHeader(eo), nil, detailWithStatus(eo)
 }
 
+// compatibleSetting setting 的请求交给 SettingApi 处理，dry_run 也由 SettingApi 处理
 func (oe *WorkerApi) compatibleSetting(profession string, r *http.Request, params httprouter.Params) (isSkip bool, status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
 	if strings.ToLower(profession) != Setting {
 		isSkip = false
 		return
 	}
 	isSkip = true
+	if tenantOf(r) != "" {
+		// setting 为集群级别的配置，不区分租户
+		return isSkip, http.StatusForbidden, nil, nil, fmt.Sprintf("profession %s not support tenant", profession)
+	}
 	status, header, events, body = oe.settingRequest(r, params)
 	return
 }
+
+// etagHeader 返回带有worker版本的header，写操作可以通过If-Match带上该版本
+func etagHeader(w *WorkerInfo) http.Header {
+	header := make(http.Header)
+	header.Set("ETag", fmt.Sprintf("%q", w.Version()))
+	return header
+}
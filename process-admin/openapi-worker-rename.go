@@ -0,0 +1,87 @@
+package process_admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/eolinker/eosc"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RenameArg 重命名的请求参数
+type RenameArg struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// RenameResult 重命名的结果，updated 为修改了引用的worker
+type RenameResult struct {
+	Worker  interface{}   `json:"worker"`
+	Updated []interface{} `json:"updated"`
+	Removed string        `json:"removed"`
+}
+
+// rename POST /api/:profession/:name/rename
+// 新建worker并修改所有引用方后删除原worker，所有事件在同一个批次中提交
+func (oe *WorkerApi) rename(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	profession := params.ByName("profession")
+	name := tenantName(r, params.ByName("name"))
+	p, has := oe.workers.professions.Get(profession)
+	if !has {
+		return http.StatusNotFound, nil, nil, fmt.Sprintf("invalid profession:%s", profession)
+	}
+	if p.Mod == eosc.ProfessionConfig_Singleton {
+		return http.StatusForbidden, nil, nil, fmt.Sprintf("not allow rename %s for %s", name, profession)
+	}
+	org, err := oe.workers.GetEmployee(profession, name)
+	if err != nil {
+		return http.StatusNotFound, nil, nil, err
+	}
+	decoder, err := GetData(r)
+	if err != nil {
+		return http.StatusInternalServerError, nil, nil, err
+	}
+	arg := new(RenameArg)
+	if err := decoder.UnMarshal(arg); err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	if arg.Name == "" {
+		return http.StatusBadRequest, nil, nil, "require name"
+	}
+	oldId := org.config.Id
+
+	// 在副本上执行，确认所有引用方都能使用新id后直接提交副本
+	clone := oe.workers.Clone()
+	created, updated, err := clone.Rename(profession, name, tenantName(r, arg.Name))
+	if err != nil {
+		return http.StatusBadRequest, nil, nil, err
+	}
+	if isDryRun(r) {
+		return http.StatusOK, nil, nil, newRenameResult(created, updated, oldId)
+	}
+
+	ids := make([]string, 0, len(updated)+2)
+	changed := make([]*WorkerInfo, 0, len(updated)+2)
+	ids = append(ids, created.config.Id)
+	changed = append(changed, created)
+	for _, w := range updated {
+		ids = append(ids, w.config.Id)
+		changed = append(changed, w)
+	}
+	ids = append(ids, oldId)
+	changed = append(changed, nil)
+	events = oe.workers.commitEvents(clone, ids, changed)
+	return http.StatusOK, etagHeader(created), events, newRenameResult(created, updated, oldId)
+}
+
+func newRenameResult(created *WorkerInfo, updated []*WorkerInfo, removed string) *RenameResult {
+	result := &RenameResult{
+		Worker:  created.Detail(),
+		Updated: make([]interface{}, 0, len(updated)),
+		Removed: removed,
+	}
+	for _, w := range updated {
+		result.Updated = append(result.Updated, w.Detail())
+	}
+	return result
+}
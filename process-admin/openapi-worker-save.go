@@ -1,11 +1,11 @@
 package process_admin
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/eolinker/eosc"
-	"github.com/eolinker/eosc/log"
 	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/eolinker/eosc/utils/schema"
 
 	"github.com/julienschmidt/httprouter"
 	"net/http"
@@ -35,23 +35,18 @@ func (oe *WorkerApi) Add(r *http.Request, params httprouter.Params) (status int,
 		return http.StatusInternalServerError, nil, nil, errUnmarshal
 	}
 
-	name := cb.Name
+	name := tenantName(r, cb.Name)
 
-	obj, err := oe.workers.Update(profession, name, cb.Driver, cb.Description, decoder)
-	if err != nil {
-		return http.StatusInternalServerError, nil, nil, err
+	if isDryRun(r) {
+		return oe.dryRun(func(ws *Workers) (*WorkerInfo, error) {
+			return ws.Update(profession, name, cb.Driver, cb.Description, decoder)
+		})
 	}
+	obj, err := oe.workers.Update(profession, name, cb.Driver, cb.Description, decoder)
 	if err != nil {
-		return http.StatusInternalServerError, nil, nil, err
+		return saveError(http.StatusInternalServerError, err)
 	}
-	eventData, _ := json.Marshal(obj.config)
-
-	return http.StatusOK, nil, []*open_api.EventResponse{{
-		Event:     eosc.EventSet,
-		Namespace: eosc.NamespaceWorker,
-		Key:       obj.config.Id,
-		Data:      eventData,
-	}}, obj.Detail()
+	return http.StatusOK, etagHeader(obj), oe.workers.setEvents(obj), obj.Detail()
 }
 func (oe *WorkerApi) Patch(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
 	profession := params.ByName("profession")
@@ -64,6 +59,7 @@ func (oe *WorkerApi) Patch(r *http.Request, params httprouter.Params) (status in
 	if name == "" {
 		return http.StatusInternalServerError, nil, nil, "require name"
 	}
+	name = tenantName(r, name)
 	decoder, err := GetData(r)
 	if err != nil {
 		return http.StatusInternalServerError, nil, nil, err
@@ -78,43 +74,17 @@ func (oe *WorkerApi) Patch(r *http.Request, params httprouter.Params) (status in
 		return http.StatusInternalServerError, nil, nil, "nothing to patch"
 
 	}
-	workerInfo, err := oe.workers.GetEmployee(profession, name)
-	if err != nil {
-		return 0, nil, nil, "not exist"
-	}
-	current := make(map[string]interface{})
-	json.Unmarshal(workerInfo.config.Body, &current)
-
-	for k, v := range options {
-		if v != nil {
-			log.Debug("patch set:", k, "=", v)
-			current[k] = v
-		} else {
-			log.Debug("patch delete:", k)
-
-			delete(current, k)
-		}
-	}
-	description := workerInfo.config.Description
-	if v, has := options["description"]; has {
-		description = v.(string)
-	}
-	data, _ := json.Marshal(current)
-	log.Debug("patch betfor:", string(workerInfo.config.Body))
-	log.Debug("patch after:", string(data))
-	decoder = JsonData(data)
-	obj, err := oe.workers.Update(profession, name, workerInfo.config.Driver, description, decoder)
+	if isDryRun(r) {
+		return oe.dryRun(func(ws *Workers) (*WorkerInfo, error) {
+			return ws.Patch(profession, name, options)
+		})
+	}
+	obj, err := oe.workers.Patch(profession, name, options)
 	if err != nil {
-		return http.StatusInternalServerError, nil, nil, err
+		return saveError(http.StatusInternalServerError, err)
 	}
 
-	eventData, _ := json.Marshal(obj.config)
-	return http.StatusOK, nil, []*open_api.EventResponse{{
-		Event:     eosc.EventSet,
-		Namespace: eosc.NamespaceWorker,
-		Key:       obj.config.Id,
-		Data:      eventData,
-	}}, obj.Detail()
+	return http.StatusOK, etagHeader(obj), oe.workers.setEvents(obj), obj.Detail()
 }
 func (oe *WorkerApi) Save(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
 
@@ -128,6 +98,7 @@ func (oe *WorkerApi) Save(r *http.Request, params httprouter.Params) (status int
 	if name == "" {
 		return http.StatusInternalServerError, nil, nil, "require name"
 	}
+	name = tenantName(r, name)
 	decoder, err := GetData(r)
 	if err != nil {
 		return http.StatusInternalServerError, nil, nil, err
@@ -137,18 +108,17 @@ func (oe *WorkerApi) Save(r *http.Request, params httprouter.Params) (status int
 	if errUnmarshal != nil {
 		return http.StatusInternalServerError, nil, nil, errUnmarshal
 	}
+	if isDryRun(r) {
+		return oe.dryRun(func(ws *Workers) (*WorkerInfo, error) {
+			return ws.Update(profession, name, cb.Driver, cb.Description, decoder)
+		})
+	}
 	obj, err := oe.workers.Update(profession, name, cb.Driver, cb.Description, decoder)
 	if err != nil {
-		return http.StatusInternalServerError, nil, nil, err
+		return saveError(http.StatusInternalServerError, err)
 	}
 
-	eventData, _ := json.Marshal(obj.config)
-	return http.StatusOK, nil, []*open_api.EventResponse{{
-		Event:     eosc.EventSet,
-		Namespace: eosc.NamespaceWorker,
-		Key:       obj.config.Id,
-		Data:      eventData,
-	}}, obj.Detail()
+	return http.StatusOK, etagHeader(obj), oe.workers.setEvents(obj), obj.Detail()
 }
 
 func (oe *WorkerApi) Delete(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
@@ -159,7 +129,7 @@ func (oe *WorkerApi) Delete(r *http.Request, params httprouter.Params) (status i
 	if isSkip {
 		return
 	}
-	name := params.ByName("name")
+	name := tenantName(r, params.ByName("name"))
 	id, ok := eosc.ToWorkerId(name, profession)
 	if !ok {
 		return http.StatusNotFound, nil, nil, fmt.Sprintf("invalid name:%s for %s", name, profession)
@@ -171,14 +141,59 @@ func (oe *WorkerApi) Delete(r *http.Request, params httprouter.Params) (status i
 	if p.Mod == eosc.ProfessionConfig_Singleton {
 		return http.StatusForbidden, nil, nil, fmt.Sprintf("not allow delete %s for %s", name, profession)
 	}
+	if r.URL.Query().Get("cascade") == "true" {
+		return oe.cascadeDelete(tenantOf(r), id, isDryRun(r))
+	}
 	wInfo, err := oe.workers.Delete(id)
 	if err != nil {
 		return 404, nil, nil, err
 	}
-	return http.StatusOK, nil, []*open_api.EventResponse{{
-		Event:     eosc.EventDel,
-		Namespace: eosc.NamespaceWorker,
-		Key:       id,
-		Data:      nil,
-	}}, wInfo.Detail()
+	return http.StatusOK, nil, oe.workers.delEvents(id), wInfo.Detail()
+}
+
+// DryRunResult dry_run 的返回结果
+type DryRunResult struct {
+	Worker    interface{} `json:"worker"`
+	Variables []string    `json:"variables"`
+	Requires  []string    `json:"requires"`
+}
+
+func isDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dry_run") == "true"
+}
+
+// dryRun 在副本上执行完整的校验及创建流程，不产生事件
+func (oe *WorkerApi) dryRun(handler func(ws *Workers) (*WorkerInfo, error)) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	ws := oe.workers.Clone()
+	obj, err := handler(ws)
+	if err != nil {
+		return saveError(http.StatusBadRequest, err)
+	}
+	result := &DryRunResult{
+		Worker:    obj.Detail(),
+		Variables: make([]string, 0),
+		Requires:  make([]string, 0),
+	}
+	if vs, ok := ws.variables.(*readonlyVariables); ok && vs.Used(obj.config.Id) != nil {
+		result.Variables = vs.Used(obj.config.Id)
+	}
+	if rs := ws.requireManager.Requires(obj.config.Id); rs != nil {
+		result.Requires = rs
+	}
+	return http.StatusOK, nil, nil, result
+}
+
+// ValidateResult 配置校验失败时的返回，fields 为各字段的错误
+type ValidateResult struct {
+	Error  string                 `json:"error"`
+	Fields schema.ValidationError `json:"fields"`
+}
+
+// saveError 配置校验失败时返回400及字段错误，其他错误使用status返回
+func saveError(status int, err error) (int, http.Header, []*open_api.EventResponse, interface{}) {
+	var fields schema.ValidationError
+	if errors.As(err, &fields) {
+		return http.StatusBadRequest, nil, nil, &ValidateResult{Error: err.Error(), Fields: fields}
+	}
+	return status, nil, nil, err
 }
@@ -0,0 +1,146 @@
+package process_admin
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eolinker/eosc"
+	grpc_unixsocket "github.com/eolinker/eosc/grpc-unixsocket"
+	"github.com/eolinker/eosc/log"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/eolinker/eosc/service"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	workerStatusTimeout = time.Second * 3
+	// workerStatusInterval 后台刷新worker运行状态的间隔
+	workerStatusInterval = time.Second * 5
+)
+
+// statusCache worker详情使用的运行状态，由后台定时从master获取，查询详情时不请求master
+// admin只运行在leader上，状态只包含leader节点的worker进程，其他节点的运行状态需要在各节点上查看
+var statusCache = &workerStatusCache{data: make(map[string]*WorkerStatus)}
+
+type workerStatusCache struct {
+	lock sync.RWMutex
+	data map[string]*WorkerStatus
+}
+
+func (c *workerStatusCache) get(id string) (*WorkerStatus, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	s, has := c.data[id]
+	if !has {
+		return nil, false
+	}
+	v := *s
+	return &v, true
+}
+
+func (c *workerStatusCache) set(list []*WorkerStatus) {
+	data := make(map[string]*WorkerStatus, len(list))
+	for _, s := range list {
+		v := *s
+		data[s.Id] = &v
+	}
+	c.lock.Lock()
+	c.data = data
+	c.lock.Unlock()
+}
+
+// watchWorkerStatus 定时刷新worker运行状态，获取失败时保留上一次的结果
+func watchWorkerStatus(ctx context.Context) {
+	ticker := time.NewTicker(workerStatusInterval)
+	defer ticker.Stop()
+	for {
+		if list, err := workerStatus(); err == nil {
+			statusCache.set(list)
+		} else {
+			log.Debug("refresh worker status:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WorkerStatus worker进程上报的运行状态，state 为 running、failed、pending
+type WorkerStatus struct {
+	Id         string `json:"id"`
+	State      string `json:"state"`
+	Error      string `json:"error,omitempty"`
+	ResetTime  string `json:"reset_time,omitempty"`
+	UpdateTime string `json:"update_time,omitempty"`
+}
+
+func formatStatusTime(ms int64) string {
+	if ms <= 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).Format(time.RFC3339)
+}
+
+// workerStatus 从本节点(leader)的master获取worker进程上报的状态，不包含其他节点
+func workerStatus() ([]*WorkerStatus, error) {
+	conn, err := grpc_unixsocket.Connect(service.ServerAddr(os.Getppid(), eosc.ProcessMaster))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), workerStatusTimeout)
+	defer cancel()
+	list, err := service.NewMasterDispatcherClient(conn).WorkerStatus(ctx, &service.EmptyRequest{})
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*WorkerStatus, 0, len(list.Status))
+	for _, s := range list.Status {
+		rs = append(rs, &WorkerStatus{
+			Id:         s.Id,
+			State:      s.State,
+			Error:      s.Error,
+			ResetTime:  formatStatusTime(s.ResetTime),
+			UpdateTime: formatStatusTime(s.UpdateTime),
+		})
+	}
+	return rs, nil
+}
+
+// statusList GET /status/workers 返回leader节点上worker的运行状态
+func (oe *WorkerApi) statusList(r *http.Request, params httprouter.Params) (status int, header http.Header, events []*open_api.EventResponse, body interface{}) {
+	list, err := workerStatus()
+	if err != nil {
+		return http.StatusServiceUnavailable, nil, nil, err
+	}
+	statusCache.set(list)
+	// 只返回请求租户下的worker
+	tenant := tenantOf(r)
+	rs := make([]*WorkerStatus, 0, len(list))
+	for _, s := range list {
+		if t, _ := eosc.SplitTenant(s.Id); t == tenant {
+			s.Id = relativeRequire(tenant, s.Id)
+			rs = append(rs, s)
+		}
+	}
+	return 200, nil, nil, rs
+}
+
+// detailWithStatus 在worker详情中加上后台刷新的运行状态，没有状态时只返回详情
+func detailWithStatus(w *WorkerInfo) interface{} {
+	detail := w.toDetails()
+	rs := make(map[string]interface{}, len(detail)+1)
+	for k, v := range detail {
+		rs[k] = v
+	}
+	if s, has := statusCache.get(w.config.Id); has {
+		s.Id = w.Id()
+		rs["status"] = s
+	}
+	return rs
+}
@@ -13,6 +13,7 @@ type WorkerApi struct {
 }
 
 func (oe *WorkerApi) Register(router *httprouter.Router) {
+	router.GET("/api", open_api.CreateHandleFunc(oe.search))
 	router.GET("/api/:profession", open_api.CreateHandleFunc(oe.getEmployeesByProfession))
 	router.GET("/api/:profession/:name", open_api.CreateHandleFunc(oe.getEmployeeByName))
 	router.POST("/api/:profession", open_api.CreateHandleFunc(oe.Add))
@@ -20,6 +21,17 @@ func (oe *WorkerApi) Register(router *httprouter.Router) {
 	router.POST("/api/:profession/:name", open_api.CreateHandleFunc(oe.Save))
 	router.DELETE("/api/:profession/:name", open_api.CreateHandleFunc(oe.Delete))
 	router.PATCH("/api/:profession/:name", open_api.CreateHandleFunc(oe.Patch))
+	router.PATCH("/api/:profession", open_api.CreateHandleFunc(oe.bulkPatch))
+	router.DELETE("/api/:profession", open_api.CreateHandleFunc(oe.bulkDelete))
+	router.GET("/api/:profession/:name/history", open_api.CreateHandleFunc(oe.history))
+	router.GET("/api/:profession/:name/history/:rev", open_api.CreateHandleFunc(oe.historyRevision))
+	router.POST("/api/:profession/:name/rollback/:rev", open_api.CreateHandleFunc(oe.rollback))
+	router.POST("/api/:profession/:name/rename", open_api.CreateHandleFunc(oe.rename))
+	router.POST("/api/:profession/:name/disable", open_api.CreateHandleFunc(oe.disable))
+	router.POST("/api/:profession/:name/enable", open_api.CreateHandleFunc(oe.enable))
+	router.GET("/api/:profession/:name/graph", open_api.CreateHandleFunc(oe.workerGraph))
+	router.GET("/graph", open_api.CreateHandleFunc(oe.graph))
+	router.GET("/status/workers", open_api.CreateHandleFunc(oe.statusList))
 
 }
 
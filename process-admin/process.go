@@ -80,6 +80,10 @@ type ProcessAdmin struct {
 func (pa *ProcessAdmin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	pa.apiLocker.Lock()
 	defer pa.apiLocker.Unlock()
+	if err := readTenant(r); err != nil {
+		writeTenantError(w, err)
+		return
+	}
 	pa.router.ServeHTTP(w, r)
 }
 
@@ -150,7 +154,7 @@ func NewProcessAdmin(parent context.Context, arg map[string]map[string][]byte) (
 	ps = NewProfessionsRequire(ps, extenderRequire)
 	ps.Reset(professionConfig(arg[eosc.NamespaceProfession]))
 
-	vd := variable.NewVariables(arg[eosc.NamespaceVariable])
+	vd := variable.NewCheckVariables(arg[eosc.NamespaceVariable])
 	wd := NewWorkerDatas(filerSetting(arg[eosc.NamespaceWorker], Setting, false))
 
 	ws := NewWorkers()
@@ -160,14 +164,18 @@ func NewProcessAdmin(parent context.Context, arg map[string]map[string][]byte) (
 	bean.Check()
 	settingApi := NewSettingApi(filerSetting(arg[eosc.NamespaceWorker], Setting, true), ws, vd)
 
-	ws.Init(ps, wd, vd)
+	ws.Init(ps, wd, vd, NewWorkerHistory(arg[eosc.NamespaceHistory], historyMaxRevision))
 
 	// openAPI handler register
 	NewProfessionApi(ps, wd).Register(p.router)
 	NewWorkerApi(ws, settingApi.request).Register(p.router)
 	settingApi.RegisterSetting(p.router)
-	NewExportApi(extenderData, ps, ws).Register(p.router)
+	authApi := NewAuthApi(arg[eosc.NamespaceAuth])
+	NewExportApi(extenderData, ps, ws, authApi).Register(p.router)
 	NewVariableApi(extenderData, ws, vd, setting.GetSettings()).Register(p.router)
+	NewTransactionApi(ws, authApi).Register(p.router)
+	authApi.Register(p.router)
+	NewOpenApiDoc(ps).Register(p.router)
 
 	p.router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response := &open_api.Response{
@@ -184,6 +192,7 @@ func NewProcessAdmin(parent context.Context, arg map[string]map[string][]byte) (
 	})
 
 	p.OpenApiServer()
+	go watchWorkerStatus(parent)
 
 	return p, nil
 }
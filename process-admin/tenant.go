@@ -0,0 +1,266 @@
+package process_admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/eolinker/eosc"
+	open_api "github.com/eolinker/eosc/open-api"
+	"github.com/eolinker/eosc/utils/config"
+	"github.com/eolinker/eosc/variable"
+)
+
+const (
+	// HeaderTenant 请求的租户，也可以使用 /t/{tenant}/ 前缀指定，不指定时为默认租户
+	HeaderTenant     = "X-Tenant"
+	tenantPathPrefix = "/t/"
+)
+
+var (
+	ErrorNotShared = errors.New("not shared")
+
+	tenantReg = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+	// variableRefReg 指定了namespace的变量引用，如 ${key@namespace:-default|lower}
+	variableRefReg = regexp.MustCompile(`(\$?)\$\{([^{}@:|]*)@([^{}:|]*)([^{}]*)\}`)
+
+	// tenantResources 支持租户的接口(第一级路径)，其他接口为集群级别的配置，只读请求不受限制
+	tenantResources = map[string]bool{
+		"api":         true,
+		"variable":    true,
+		"export":      true,
+		"transaction": true,
+		"batch":       true,
+		"graph":       true,
+		"status":      true,
+	}
+)
+
+// readTenant 读取请求的租户，/t/{tenant}/ 前缀优先于header，读取后去掉路径前缀并统一写入header
+func readTenant(r *http.Request) error {
+	tenant := r.Header.Get(HeaderTenant)
+	if strings.HasPrefix(r.URL.Path, tenantPathPrefix) {
+		path := strings.TrimPrefix(r.URL.Path, tenantPathPrefix)
+		index := strings.Index(path, "/")
+		if index < 0 {
+			tenant, path = path, "/"
+		} else {
+			tenant, path = path[:index], path[index:]
+		}
+		r.URL.Path = path
+		r.URL.RawPath = ""
+	}
+	tenant = strings.ToLower(tenant)
+	if tenant == eosc.TenantDefault {
+		tenant = ""
+	}
+	if tenant == "" {
+		r.Header.Del(HeaderTenant)
+		return nil
+	}
+	if !tenantReg.MatchString(tenant) {
+		return fmt.Errorf("invalid tenant:%s", tenant)
+	}
+	resource := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0]
+	if !tenantResources[resource] && r.Method != http.MethodGet {
+		return fmt.Errorf("%s not support tenant", r.URL.Path)
+	}
+	r.Header.Set(HeaderTenant, tenant)
+	return nil
+}
+
+func writeTenantError(w http.ResponseWriter, err error) {
+	response := &open_api.Response{
+		StatusCode: http.StatusBadRequest,
+		Data:       []byte(err.Error()),
+	}
+	data, _ := json.Marshal(response)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+// tenantOf 返回请求的租户，默认租户返回空
+func tenantOf(r *http.Request) string {
+	return r.Header.Get(HeaderTenant)
+}
+
+// tenantName 返回请求租户下的worker名称
+func tenantName(r *http.Request, name string) string {
+	return scopedName(tenantOf(r), name)
+}
+
+// scopedName 返回租户下的worker名称，默认租户下带租户前缀的名称会被当作无效名称
+func scopedName(tenant, name string) string {
+	if tenant == "" && strings.Contains(name, eosc.TenantSeparator) {
+		return eosc.TenantDefault + eosc.TenantSeparator + name
+	}
+	return eosc.ToTenantName(tenant, name)
+}
+
+// tenantNamespace 租户下的变量namespace保存为 {tenant}/{namespace}，节点提供的namespace所有租户共用，不加前缀
+func tenantNamespace(tenant, namespace string) string {
+	if namespace == "" {
+		namespace = eosc.TenantDefault
+	}
+	if _, has := variable.GetProvider(namespace); has {
+		return namespace
+	}
+	return eosc.ToTenantName(tenant, namespace)
+}
+
+// isProviderNamespace namespace是否由节点提供，带租户前缀的同名namespace也视为节点提供
+func isProviderNamespace(namespace string) bool {
+	_, has := variable.GetProvider(namespace[strings.LastIndex(namespace, eosc.TenantSeparator)+1:])
+	return has
+}
+
+// absoluteRequire 将租户内的引用转换为worker id，不带租户的引用指向当前租户，default/ 前缀指向默认租户
+func absoluteRequire(tenant, id string) string {
+	if strings.Contains(id, "${") {
+		return id
+	}
+	if strings.Contains(id, eosc.TenantSeparator) {
+		t, name := eosc.SplitTenant(id)
+		return eosc.ToTenantName(t, name)
+	}
+	return eosc.ToTenantName(tenant, id)
+}
+
+// relativeRequire 将worker id转换为租户内的引用，与 absoluteRequire 相反
+func relativeRequire(tenant, id string) string {
+	if tenant == "" || strings.Contains(id, "${") {
+		return id
+	}
+	t, name := eosc.SplitTenant(id)
+	switch t {
+	case tenant:
+		return name
+	case "":
+		return eosc.TenantDefault + eosc.TenantSeparator + name
+	}
+	return id
+}
+
+// absoluteBody 将租户视角的body转换为保存的格式，引用改为worker id，变量namespace加上租户前缀
+func absoluteBody(t reflect.Type, tenant string, body []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	value, changed := config.MapRequire(t, value, func(id string) (string, bool) {
+		v := absoluteRequire(tenant, id)
+		return v, v != id
+	})
+	if tenant != "" {
+		var c bool
+		value, c = mapVariableNamespace(value, func(namespace string) string {
+			return tenantNamespace(tenant, namespace)
+		})
+		changed = changed || c
+	}
+	if !changed {
+		return body
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+// relativeBody 将保存的body转换为租户视角，与 absoluteBody 相反
+func relativeBody(t reflect.Type, tenant string, value map[string]interface{}) map[string]interface{} {
+	if tenant == "" {
+		return value
+	}
+	config.MapRequire(t, value, func(id string) (string, bool) {
+		v := relativeRequire(tenant, id)
+		return v, v != id
+	})
+	prefix := tenant + eosc.TenantSeparator
+	rs, _ := mapVariableNamespace(value, func(namespace string) string {
+		return strings.TrimPrefix(namespace, prefix)
+	})
+	m, _ := rs.(map[string]interface{})
+	return m
+}
+
+// mapVariableNamespace 修改所有字符串中变量引用的namespace
+func mapVariableNamespace(value interface{}, fn func(namespace string) string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "${") {
+			return v, false
+		}
+		rs := variableRefReg.ReplaceAllStringFunc(v, func(ref string) string {
+			sub := variableRefReg.FindStringSubmatch(ref)
+			if sub[1] != "" {
+				// $${ 转义的不是变量引用
+				return ref
+			}
+			if _, has := variable.GetProvider(sub[3]); has {
+				// 节点提供的namespace不区分租户
+				return ref
+			}
+			return fmt.Sprintf("${%s@%s%s}", sub[2], fn(sub[3]), sub[4])
+		})
+		return rs, rs != v
+	case map[string]interface{}:
+		changed := false
+		for k, item := range v {
+			if nv, c := mapVariableNamespace(item, fn); c {
+				v[k] = nv
+				changed = true
+			}
+		}
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i, item := range v {
+			if nv, c := mapVariableNamespace(item, fn); c {
+				v[i] = nv
+				changed = true
+			}
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+// checkTenantRequires 跨租户只能引用共享的worker，被其他租户引用的worker不能取消共享
+func (oe *Workers) checkTenantRequires(id string, shared bool, requires []string) error {
+	tenant, _ := eosc.SplitTenant(id)
+	for _, rid := range requires {
+		if t, _ := eosc.SplitTenant(rid); t == tenant {
+			continue
+		}
+		target, has := oe.data.GetInfo(rid)
+		if !has || !target.config.Shared {
+			return fmt.Errorf("require %s of other tenant:%w", rid, ErrorNotShared)
+		}
+	}
+	if shared {
+		return nil
+	}
+	for _, by := range oe.requireManager.RequireBy(id) {
+		if t, _ := eosc.SplitTenant(by); t != tenant {
+			return fmt.Errorf("%s is required by %s of other tenant:%w", id, by, ErrorNotShared)
+		}
+	}
+	return nil
+}
+
+// readShared 从body的shared字段读取是否允许其他租户引用
+func readShared(body []byte) bool {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(body, &m); err != nil {
+		return false
+	}
+	shared, _ := m[workerSharedField].(bool)
+	return shared
+}
@@ -0,0 +1,33 @@
+package process_admin
+
+import "github.com/eolinker/eosc"
+
+// readonlyVariables 校验副本使用的变量，引用关系只记录在副本中，不修改原有数据
+type readonlyVariables struct {
+	eosc.IVariable
+	used map[string][]string
+}
+
+func newReadonlyVariables(variables eosc.IVariable) *readonlyVariables {
+	return &readonlyVariables{IVariable: variables, used: make(map[string][]string)}
+}
+
+func (r *readonlyVariables) SetVariablesById(id string, variables []string) {
+	r.used[id] = variables
+}
+
+func (r *readonlyVariables) RemoveRequire(id string) {
+	delete(r.used, id)
+}
+
+func (r *readonlyVariables) GetVariablesById(id string) []string {
+	if vs, has := r.used[id]; has {
+		return vs
+	}
+	return r.IVariable.GetVariablesById(id)
+}
+
+// Used 返回副本中worker使用的变量
+func (r *readonlyVariables) Used(id string) []string {
+	return r.used[id]
+}
@@ -67,6 +67,11 @@ func (w *WorkerDatas) Clone() *WorkerDatas {
 	return &WorkerDatas{data: w.data.Clone()}
 }
 
+// replace 替换为副本的数据，已注入到其他组件的 WorkerDatas 保持不变
+func (w *WorkerDatas) replace(o *WorkerDatas) {
+	w.data = o.data
+}
+
 func (w *WorkerDatas) Count() int {
 	return w.data.Count()
 }
@@ -0,0 +1,235 @@
+package process_admin
+
+import (
+	"fmt"
+
+	"github.com/eolinker/eosc"
+	"sort"
+	"strings"
+)
+
+const (
+	GraphNodeWorker   = "worker"
+	GraphNodeVariable = "variable"
+
+	GraphEdgeRequire  = "require"
+	GraphEdgeVariable = "variable"
+
+	// graphVariablePrefix 变量节点id的前缀，避免与worker id(name@profession)冲突
+	graphVariablePrefix = "variable:"
+)
+
+// GraphNode 依赖图中的节点，worker节点的id为worker id，变量节点的id为 variable:{变量名}
+type GraphNode struct {
+	Id         string `json:"id"`
+	Type       string `json:"type"`
+	Profession string `json:"profession,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Driver     string `json:"driver,omitempty"`
+}
+
+// GraphEdge 依赖关系，from 依赖 to
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+type Graph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+// graphBuilder 只包含租户下的worker，以及被这些worker引用的其他租户的共享worker
+type graphBuilder struct {
+	workers *Workers
+	tenant  string
+	nodes   map[string]*GraphNode
+	edges   map[GraphEdge]struct{}
+}
+
+func newGraphBuilder(workers *Workers, tenant string) *graphBuilder {
+	return &graphBuilder{
+		workers: workers,
+		tenant:  tenant,
+		nodes:   make(map[string]*GraphNode),
+		edges:   make(map[GraphEdge]struct{}),
+	}
+}
+
+// Graph 返回租户下所有worker及变量的依赖图，包含被引用的其他租户的共享worker
+func (oe *Workers) Graph(tenant string) *Graph {
+	b := newGraphBuilder(oe, tenant)
+	for _, id := range oe.data.Keys() {
+		if t, _ := eosc.SplitTenant(id); t != tenant {
+			continue
+		}
+		b.addNode(id)
+		for _, next := range b.neighbors(id) {
+			b.addNode(next)
+		}
+	}
+	return b.build()
+}
+
+// SubGraph 返回与worker相关的依赖图，包含其依赖的以及依赖它的节点，depth 为展开的层数，小于等于0时不限制
+// 只包含worker所在租户的节点以及被引用的其他租户的共享worker
+func (oe *Workers) SubGraph(profession, name string, depth int) (*Graph, error) {
+	w, err := oe.GetEmployee(profession, name)
+	if err != nil {
+		return nil, err
+	}
+	b := newGraphBuilder(oe, w.config.Tenant)
+	b.addNode(w.config.Id)
+	current := []string{w.config.Id}
+	for level := 0; len(current) > 0 && (depth <= 0 || level < depth); level++ {
+		next := make([]string, 0)
+		for _, id := range current {
+			for _, n := range b.neighbors(id) {
+				if _, has := b.nodes[n]; has {
+					continue
+				}
+				b.addNode(n)
+				next = append(next, n)
+			}
+		}
+		current = next
+	}
+	for _, id := range current {
+		// 最外层节点之间的边
+		b.neighbors(id)
+	}
+	return b.build(), nil
+}
+
+// neighbors 记录与节点相关的边，并返回相邻的节点
+// 其他租户的worker只有共享并且被引用时返回，引用方只返回租户下的worker
+func (b *graphBuilder) neighbors(id string) []string {
+	rs := make([]string, 0)
+	if strings.HasPrefix(id, graphVariablePrefix) {
+		for _, wid := range b.workers.variables.GetIdsByVariable(strings.TrimPrefix(id, graphVariablePrefix)) {
+			if !b.inTenant(wid) {
+				continue
+			}
+			b.addEdge(wid, id, GraphEdgeVariable)
+			rs = append(rs, wid)
+		}
+		return rs
+	}
+	for _, rid := range b.workers.requireManager.Requires(id) {
+		if !b.inTenant(rid) && !b.shared(rid) {
+			continue
+		}
+		b.addEdge(id, rid, GraphEdgeRequire)
+		rs = append(rs, rid)
+	}
+	for _, bid := range b.workers.requireManager.RequireBy(id) {
+		if !b.inTenant(bid) {
+			continue
+		}
+		b.addEdge(bid, id, GraphEdgeRequire)
+		rs = append(rs, bid)
+	}
+	for _, v := range b.workers.variables.GetVariablesById(id) {
+		vid := graphVariablePrefix + v
+		b.addEdge(id, vid, GraphEdgeVariable)
+		rs = append(rs, vid)
+	}
+	return rs
+}
+
+func (b *graphBuilder) inTenant(id string) bool {
+	t, _ := eosc.SplitTenant(id)
+	return t == b.tenant
+}
+
+func (b *graphBuilder) shared(id string) bool {
+	w, has := b.workers.data.GetInfo(id)
+	return has && w.config.Shared
+}
+
+func (b *graphBuilder) addNode(id string) {
+	if strings.HasPrefix(id, graphVariablePrefix) {
+		if _, has := b.nodes[id]; !has {
+			b.nodes[id] = &GraphNode{Id: id, Type: GraphNodeVariable, Name: strings.TrimPrefix(id, graphVariablePrefix)}
+		}
+		return
+	}
+	b.addWorker(id)
+}
+
+func (b *graphBuilder) addWorker(id string) {
+	if _, has := b.nodes[id]; has {
+		return
+	}
+	node := &GraphNode{Id: id, Type: GraphNodeWorker}
+	if w, has := b.workers.data.GetInfo(id); has {
+		node.Profession = w.config.Profession
+		node.Name = w.config.Name
+		node.Driver = w.config.Driver
+	}
+	b.nodes[id] = node
+}
+
+func (b *graphBuilder) addEdge(from, to, typ string) {
+	b.edges[GraphEdge{From: from, To: to, Type: typ}] = struct{}{}
+}
+
+// build 生成结果，只保留两端都在图中的边
+func (b *graphBuilder) build() *Graph {
+	g := &Graph{
+		Nodes: make([]*GraphNode, 0, len(b.nodes)),
+		Edges: make([]*GraphEdge, 0, len(b.edges)),
+	}
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	for e := range b.edges {
+		_, hasFrom := b.nodes[e.From]
+		_, hasTo := b.nodes[e.To]
+		if !hasFrom || !hasTo {
+			continue
+		}
+		edge := e
+		g.Edges = append(g.Edges, &edge)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Id < g.Nodes[j].Id
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+// DOT 输出为Graphviz格式，变量节点使用椭圆，变量引用使用虚线
+func (g *Graph) DOT() string {
+	buf := &strings.Builder{}
+	buf.WriteString("digraph eosc {\n")
+	buf.WriteString("\trankdir=LR;\n")
+	buf.WriteString("\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		switch n.Type {
+		case GraphNodeVariable:
+			fmt.Fprintf(buf, "\t%q [shape=ellipse,label=%q];\n", n.Id, "${"+n.Name+"}")
+		default:
+			label := n.Id
+			if n.Driver != "" {
+				label = fmt.Sprintf("%s\\n(%s)", n.Id, n.Driver)
+			}
+			fmt.Fprintf(buf, "\t%q [label=\"%s\"];\n", n.Id, strings.ReplaceAll(label, `"`, `\"`))
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Type == GraphEdgeVariable {
+			fmt.Fprintf(buf, "\t%q -> %q [style=dashed];\n", e.From, e.To)
+			continue
+		}
+		fmt.Fprintf(buf, "\t%q -> %q;\n", e.From, e.To)
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
@@ -0,0 +1,122 @@
+package process_admin
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/log"
+	open_api "github.com/eolinker/eosc/open-api"
+)
+
+// historyMaxRevision 每个worker保留的最大版本数
+const historyMaxRevision = 10
+
+// WorkerRevision worker的一个历史版本
+type WorkerRevision struct {
+	Rev         int64           `json:"rev"`
+	Driver      string          `json:"driver"`
+	Description string          `json:"description"`
+	Update      string          `json:"update"`
+	Body        json.RawMessage `json:"body"`
+}
+
+// WorkerHistory 记录worker的历史版本，数据存储在 NamespaceHistory 下，key为worker id
+type WorkerHistory struct {
+	data eosc.Untyped[string, []*WorkerRevision]
+	max  int
+}
+
+func NewWorkerHistory(initData map[string][]byte, max int) *WorkerHistory {
+	h := &WorkerHistory{data: eosc.BuildUntyped[string, []*WorkerRevision](), max: max}
+	for id, d := range initData {
+		revisions := make([]*WorkerRevision, 0)
+		if err := json.Unmarshal(d, &revisions); err != nil {
+			log.Warn("read worker history:", id, ":", err)
+			continue
+		}
+		h.data.Set(id, revisions)
+	}
+	return h
+}
+
+// seed 没有历史版本的worker以当前配置作为第一个版本，保证第一次修改后可以回滚，随下一次 Append 的事件保存
+func (h *WorkerHistory) seed(info *WorkerInfo) {
+	if revisions, has := h.data.Get(info.config.Id); has && len(revisions) > 0 {
+		return
+	}
+	h.data.Set(info.config.Id, []*WorkerRevision{{
+		Rev:         1,
+		Driver:      info.config.Driver,
+		Description: info.config.Description,
+		Update:      info.config.Update,
+		Body:        info.config.Body,
+	}})
+}
+
+// Append 记录新版本，与最新版本相同时不记录
+func (h *WorkerHistory) Append(info *WorkerInfo) (*open_api.EventResponse, bool) {
+	id := info.config.Id
+	revisions, _ := h.data.Get(id)
+	var rev int64 = 1
+	if len(revisions) > 0 {
+		last := revisions[len(revisions)-1]
+		if last.Driver == info.config.Driver && last.Description == info.config.Description && bytes.Equal(last.Body, info.config.Body) {
+			return nil, false
+		}
+		rev = last.Rev + 1
+	}
+	revision := &WorkerRevision{
+		Rev:         rev,
+		Driver:      info.config.Driver,
+		Description: info.config.Description,
+		Update:      info.config.Update,
+		Body:        info.config.Body,
+	}
+	list := make([]*WorkerRevision, 0, len(revisions)+1)
+	list = append(list, revisions...)
+	list = append(list, revision)
+	if len(list) > h.max {
+		list = list[len(list)-h.max:]
+	}
+	h.data.Set(id, list)
+
+	data, _ := json.Marshal(list)
+	return &open_api.EventResponse{
+		Event:     eosc.EventSet,
+		Namespace: eosc.NamespaceHistory,
+		Key:       id,
+		Data:      data,
+	}, true
+}
+
+// Delete 删除worker的所有历史版本
+func (h *WorkerHistory) Delete(id string) (*open_api.EventResponse, bool) {
+	if _, has := h.data.Del(id); !has {
+		return nil, false
+	}
+	return &open_api.EventResponse{
+		Event:     eosc.EventDel,
+		Namespace: eosc.NamespaceHistory,
+		Key:       id,
+		Data:      nil,
+	}, true
+}
+
+func (h *WorkerHistory) List(id string) []*WorkerRevision {
+	revisions, has := h.data.Get(id)
+	if !has {
+		return []*WorkerRevision{}
+	}
+	return revisions
+}
+
+func (h *WorkerHistory) Get(id string, rev int64) (*WorkerRevision, bool) {
+	revisions, _ := h.data.Get(id)
+	for _, r := range revisions {
+		if r.Rev == rev {
+			return r, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,29 @@
+package process_admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eolinker/eosc"
+)
+
+func TestWorkerHistory_firstSave(t *testing.T) {
+	data, _ := json.Marshal(&eosc.WorkerConfig{
+		Id:         "a@router",
+		Profession: "router",
+		Name:       "a",
+		Driver:     "http",
+		Body:       []byte(`{"value":"v1"}`),
+	})
+	ws := newTestWorkersWithData(t, nil, map[string][]byte{"a@router": data})
+	status, events := doTransaction(NewTransactionApi(ws, nil), `{"operations":[
+		{"action":"update","profession":"router","name":"a","body":{"driver":"http","value":"v2"}}]}`)
+	if status != http.StatusOK || len(events) != 2 || events[1].Namespace != eosc.NamespaceHistory {
+		t.Fatalf("status = %d, events = %v", status, events)
+	}
+	revisions := ws.history.List("a@router")
+	if len(revisions) != 2 || string(revisions[0].Body) != `{"value":"v1"}` || revisions[1].Rev != 2 {
+		t.Errorf("revisions = %v", revisions)
+	}
+}
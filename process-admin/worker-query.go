@@ -0,0 +1,145 @@
+package process_admin
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eolinker/eosc/utils/labels"
+)
+
+const (
+	SortByName   = "name"
+	SortByCreate = "create"
+	SortByUpdate = "update"
+)
+
+// WorkerQuery worker列表的过滤、排序及分页条件
+type WorkerQuery struct {
+	// Tenant 只查询该租户下的worker，由请求的租户决定，不从查询参数读取
+	Tenant    string
+	Driver    string
+	Name      string
+	NameRegex *regexp.Regexp
+	Keyword   string
+	Selector  labels.Selector
+	Sort      string
+	Desc      bool
+	Offset    int
+	Limit     int
+}
+
+// parseWorkerQuery 读取查询参数：limit、offset、driver、name(前缀)、name~(正则)、sort(前缀-表示倒序)、q(关键字)、selector(标签选择器)
+func parseWorkerQuery(values url.Values) (*WorkerQuery, error) {
+	q := &WorkerQuery{
+		Driver:  values.Get("driver"),
+		Name:    strings.ToLower(values.Get("name")),
+		Keyword: strings.ToLower(values.Get("q")),
+	}
+	selector, err := labels.Parse(values.Get("selector"))
+	if err != nil {
+		return nil, err
+	}
+	q.Selector = selector
+	if v := values.Get("name~"); v != "" {
+		reg, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name~:%w", err)
+		}
+		q.NameRegex = reg
+	}
+	if v := values.Get("sort"); v != "" {
+		q.Desc = strings.HasPrefix(v, "-")
+		q.Sort = strings.TrimPrefix(v, "-")
+		switch q.Sort {
+		case SortByName, SortByCreate, SortByUpdate:
+		default:
+			return nil, fmt.Errorf("invalid sort:%s", v)
+		}
+	}
+	if q.Offset, err = readUint(values, "offset"); err != nil {
+		return nil, err
+	}
+	if q.Limit, err = readUint(values, "limit"); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func readUint(values url.Values, name string) (int, error) {
+	v := values.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s:%s", name, v)
+	}
+	return n, nil
+}
+
+func (q *WorkerQuery) match(w *WorkerInfo, appendLabels []string) bool {
+	if q.Driver != "" && !strings.EqualFold(w.config.Driver, q.Driver) {
+		return false
+	}
+	if q.Name != "" && !strings.HasPrefix(strings.ToLower(w.config.Name), q.Name) {
+		return false
+	}
+	if q.NameRegex != nil && !q.NameRegex.MatchString(w.config.Name) {
+		return false
+	}
+	if q.Keyword != "" && !q.matchKeyword(w, appendLabels) {
+		return false
+	}
+	return q.Selector.Matches(w.config.Labels)
+}
+
+// matchKeyword 在名称、描述以及profession的AppendLabels字段中查找关键字
+func (q *WorkerQuery) matchKeyword(w *WorkerInfo, appendLabels []string) bool {
+	if strings.Contains(strings.ToLower(w.config.Name), q.Keyword) || strings.Contains(strings.ToLower(w.config.Description), q.Keyword) {
+		return true
+	}
+	if len(appendLabels) == 0 {
+		return false
+	}
+	detail := w.toDetails()
+	for _, label := range appendLabels {
+		if v, has := detail[label]; has && strings.Contains(strings.ToLower(fmt.Sprint(v)), q.Keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// page 排序并分页，返回当前页以及总数
+func (q *WorkerQuery) page(list []*WorkerInfo) ([]*WorkerInfo, int) {
+	if q.Sort != "" {
+		less := func(a, b *WorkerInfo) bool {
+			switch q.Sort {
+			case SortByCreate:
+				return a.config.Create < b.config.Create
+			case SortByUpdate:
+				return a.config.Update < b.config.Update
+			}
+			return a.config.Name < b.config.Name
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			if q.Desc {
+				return less(list[j], list[i])
+			}
+			return less(list[i], list[j])
+		})
+	}
+	total := len(list)
+	if q.Offset >= total {
+		return []*WorkerInfo{}, total
+	}
+	list = list[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(list) {
+		list = list[:q.Limit]
+	}
+	return list, total
+}
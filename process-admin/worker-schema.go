@@ -0,0 +1,56 @@
+package process_admin
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/eolinker/eosc/log"
+	"github.com/eolinker/eosc/utils/schema"
+)
+
+// workerMetaFields worker的基础信息，与driver配置保存在同一个body中，不属于driver的配置
+var workerMetaFields = []string{"id", "name", "profession", "driver", "description", "create", "update", "version", workerLabelsField, workerDisableField, workerSharedField, workerTenantField}
+
+const (
+	workerLabelsField  = "labels"
+	workerDisableField = "disable"
+	workerSharedField  = "shared"
+	workerTenantField  = "tenant"
+)
+
+var schemaCache sync.Map
+
+// configSchema 返回driver配置类型的schema，生成失败时返回nil，不做校验
+func configSchema(t reflect.Type) *schema.Schema {
+	if v, has := schemaCache.Load(t); has {
+		return v.(*schema.Schema)
+	}
+	sc, err := schema.Generate(t, nil)
+	if err != nil {
+		log.Warn("generate schema for ", t, ":", err)
+		sc = nil
+	}
+	schemaCache.Store(t, sc)
+	return sc
+}
+
+// validateBody 使用driver配置的schema校验body，失败时返回 schema.ValidationError
+// patched 不为nil时只在修改的字段中检查未知属性
+func validateBody(t reflect.Type, body []byte, patched []string) error {
+	if t == nil || len(body) == 0 {
+		return nil
+	}
+	sc := configSchema(t)
+	if sc == nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return err
+	}
+	if patched != nil {
+		return sc.ValidatePatch(value, patched, workerMetaFields...)
+	}
+	return sc.Validate(value, workerMetaFields...)
+}
@@ -2,7 +2,10 @@ package process_admin
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/log"
+	"github.com/eolinker/eosc/utils/labels"
 	"reflect"
 )
 
@@ -13,6 +16,7 @@ type WorkerInfo struct {
 	attr         map[string]interface{}
 	info         map[string]interface{}
 	configType   reflect.Type
+	version      string
 }
 
 func NewWorkerInfo(worker eosc.IWorker, id string, profession string, name, driver, desc, create, update string, body []byte, configType reflect.Type) *WorkerInfo {
@@ -29,6 +33,8 @@ func NewWorkerInfo(worker eosc.IWorker, id string, profession string, name, driv
 			Update:      update,
 			Description: desc,
 			Body:        body,
+			Labels:      readLabelsLenient(body),
+			Shared:      readShared(body),
 		},
 		configType: configType,
 		attr:       nil,
@@ -40,10 +46,22 @@ func (w *WorkerInfo) reset(driver, desc string, body []byte, worker eosc.IWorker
 	w.config.Driver = driver
 	w.config.Description = desc
 	w.config.Body = body
+	w.config.Labels = readLabelsLenient(body)
+	w.config.Shared = readShared(body)
 	w.configType = configType
 	w.worker = worker
 	w.info = nil
 	w.attr = nil
+	w.version = ""
+}
+
+// Version 返回当前配置的版本，用于ETag
+func (w *WorkerInfo) Version() string {
+	if w.version == "" {
+		data, _ := json.Marshal(w.config)
+		w.version = eosc.SHA1(data)
+	}
+	return w.version
 }
 
 func (w *WorkerInfo) Detail() interface{} {
@@ -51,15 +69,24 @@ func (w *WorkerInfo) Detail() interface{} {
 }
 func (w *WorkerInfo) toDetails() map[string]interface{} {
 	if w.attr == nil {
-		m := make(map[string]interface{})
-		json.Unmarshal(w.config.Body, &m)
-		m["id"] = w.config.Id
+		m := w.tenantBody()
+		m["id"] = w.Id()
 		m["profession"] = w.config.Profession
 		m["name"] = w.config.Name
 		m["driver"] = w.config.Driver
 		m["description"] = w.config.Description
 		m["update"] = w.config.Update
 		m["create"] = w.config.Create
+		m["version"] = w.Version()
+		if len(w.config.Labels) > 0 {
+			m[workerLabelsField] = w.Labels()
+		}
+		if w.config.Disable {
+			m[workerDisableField] = true
+		}
+		if w.config.Tenant != "" {
+			m[workerTenantField] = w.config.Tenant
+		}
 		w.attr = m
 	}
 
@@ -72,18 +99,88 @@ func (w *WorkerInfo) Info(appendLabels ...string) interface{} {
 		for _, label := range appendLabels {
 			w.info[label] = detail[label]
 		}
-		w.info["id"] = w.config.Id
+		w.info["id"] = w.Id()
 		w.info["profession"] = w.config.Profession
 		w.info["name"] = w.config.Name
 		w.info["driver"] = w.config.Driver
 		w.info["description"] = w.config.Description
 		w.info["update"] = w.config.Update
 		w.info["create"] = w.config.Create
+		w.info["version"] = w.Version()
+		w.info[workerLabelsField] = w.Labels()
+		w.info[workerDisableField] = w.config.Disable
+		w.info[workerSharedField] = w.config.Shared
 	}
 
 	return w.info
 }
 
+// Id 返回租户视角的worker id，默认租户下与保存的id相同
+func (w *WorkerInfo) Id() string {
+	return relativeRequire(w.config.Tenant, w.config.Id)
+}
+
+// TenantName 返回带租户前缀的名称，用于通过 Workers 查询或者修改该worker
+func (w *WorkerInfo) TenantName() string {
+	return eosc.ToTenantName(w.config.Tenant, w.config.Name)
+}
+
+// tenantBody 返回租户视角的body，引用及变量去掉当前租户的前缀
+func (w *WorkerInfo) tenantBody() map[string]interface{} {
+	m := make(map[string]interface{})
+	json.Unmarshal(w.config.Body, &m)
+	if rs := relativeBody(w.configType, w.config.Tenant, m); rs != nil {
+		return rs
+	}
+	return m
+}
+
+// Labels 返回worker的标签
+func (w *WorkerInfo) Labels() map[string]string {
+	labels := make(map[string]string, len(w.config.Labels))
+	for k, v := range w.config.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// readLabels 从body的labels字段读取标签
+func readLabels(body []byte) (map[string]string, error) {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+	v, has := m[workerLabelsField]
+	if !has || v == nil {
+		return nil, nil
+	}
+	lm, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected object", workerLabelsField)
+	}
+	if len(lm) == 0 {
+		return nil, nil
+	}
+	rs := make(map[string]string, len(lm))
+	for k, lv := range lm {
+		str, ok := lv.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s: expected string", workerLabelsField, k)
+		}
+		rs[k] = str
+	}
+	return rs, labels.Validate(rs)
+}
+
+// readLabelsLenient 已保存的数据不再校验，解析失败时忽略标签
+func readLabelsLenient(body []byte) map[string]string {
+	rs, err := readLabels(body)
+	if err != nil {
+		log.Warn("read labels:", err)
+	}
+	return rs
+}
+
 func (w *WorkerInfo) Body() []byte {
 	return w.config.Body
 }
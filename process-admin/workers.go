@@ -1,14 +1,19 @@
 package process_admin
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/eolinker/eosc"
 	"github.com/eolinker/eosc/log"
+	open_api "github.com/eolinker/eosc/open-api"
 	"github.com/eolinker/eosc/require"
 
 	"github.com/eolinker/eosc/professions"
 	"github.com/eolinker/eosc/utils/config"
+	"github.com/eolinker/eosc/utils/labels"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 type Workers struct {
@@ -16,6 +21,13 @@ type Workers struct {
 	data           *WorkerDatas
 	requireManager eosc.IRequires
 	variables      eosc.IVariable
+	history        *WorkerHistory
+	// isClone 为true时表示该对象是校验用的副本，不会修改原有的worker实例
+	isClone bool
+	// lenient 为true时不做schema校验，用于加载已保存的配置
+	lenient bool
+	// patched 不为nil时只在这些字段中检查未知属性，用于PATCH
+	patched []string
 }
 
 func NewWorkers() *Workers {
@@ -24,10 +36,15 @@ func NewWorkers() *Workers {
 
 	return ws
 }
-func (oe *Workers) Init(professions professions.IProfessions, data *WorkerDatas, variables eosc.IVariable) {
+func (oe *Workers) Init(professions professions.IProfessions, data *WorkerDatas, variables eosc.IVariable, history *WorkerHistory) {
 	oe.professions = professions
 	oe.data = data
 	oe.variables = variables
+	oe.history = history
+	oe.lenient = true
+	defer func() {
+		oe.lenient = false
+	}()
 
 	ps := oe.professions.Sort()
 
@@ -44,7 +61,72 @@ func (oe *Workers) Init(professions professions.IProfessions, data *WorkerDatas,
 			}
 		}
 	}
+	if oe.history != nil {
+		for _, wd := range oe.data.List() {
+			oe.history.seed(wd)
+		}
+	}
+}
+
+// Clone 复制当前的worker数据及依赖关系，在副本上的操作不会影响原数据，用于变更前的校验
+func (oe *Workers) Clone() *Workers {
+	requireManager := require.NewRequireManager()
+	data := oe.data.Clone()
+	for _, id := range data.Keys() {
+		requireManager.Set(id, oe.requireManager.Requires(id))
+	}
+	return &Workers{
+		professions:    oe.professions,
+		data:           data,
+		requireManager: requireManager,
+		variables:      newReadonlyVariables(oe.variables),
+		isClone:        true,
+	}
+}
+
+// commit 使用校验通过的副本替换当前数据，不在当前数据上重新执行变更，保证结果与校验一致
+// 副本中删除或者重新创建了实例的worker，原实例会被销毁
+func (oe *Workers) commit(clone *Workers) {
+	for id, info := range oe.data.All() {
+		if n, has := clone.data.GetInfo(id); has {
+			if n.worker != info.worker {
+				destroyWorker(info.worker)
+			}
+			continue
+		}
+		// 副本中已删除的worker
+		destroyWorker(info.worker)
+		oe.variables.RemoveRequire(id)
+	}
+	if rv, ok := clone.variables.(*readonlyVariables); ok {
+		for id, used := range rv.used {
+			oe.variables.SetVariablesById(id, used)
+		}
+	}
+	oe.data.replace(clone.data)
+	oe.requireManager = clone.requireManager
+}
+
+func destroyWorker(worker eosc.IWorker) {
+	if destroy, ok := worker.(eosc.IWorkerDestroy); ok {
+		destroy.Destroy()
+	}
 }
+
+// commitEvents 提交副本，并按操作顺序生成事件，changed中为nil的表示该id已删除
+func (oe *Workers) commitEvents(clone *Workers, ids []string, changed []*WorkerInfo) []*open_api.EventResponse {
+	oe.commit(clone)
+	events := make([]*open_api.EventResponse, 0, len(ids))
+	for i, id := range ids {
+		if changed[i] != nil {
+			events = append(events, oe.setEvents(changed[i])...)
+		} else {
+			events = append(events, oe.delEvents(id)...)
+		}
+	}
+	return events
+}
+
 func (oe *Workers) ListEmployees(profession string) ([]interface{}, error) {
 	p, has := oe.professions.Get(profession)
 	if !has {
@@ -62,6 +144,79 @@ func (oe *Workers) ListEmployees(profession string) ([]interface{}, error) {
 
 }
 
+// Query 按条件查询worker，profession为空时查询所有profession
+func (oe *Workers) Query(profession string, q *WorkerQuery) ([]interface{}, int, error) {
+	labels := make(map[string][]string)
+	if profession != "" {
+		p, has := oe.professions.Get(profession)
+		if !has {
+			return nil, 0, eosc.ErrorProfessionNotExist
+		}
+		profession = p.Name
+		labels[p.Name] = p.AppendLabels
+	} else {
+		for _, p := range oe.professions.List() {
+			labels[p.Name] = p.AppendLabels
+		}
+	}
+	list := make([]*WorkerInfo, 0)
+	for _, w := range oe.data.List() {
+		if profession != "" && w.config.Profession != profession {
+			continue
+		}
+		if w.config.Tenant != q.Tenant {
+			continue
+		}
+		if q.match(w, labels[w.config.Profession]) {
+			list = append(list, w)
+		}
+	}
+	list, total := q.page(list)
+	vs := make([]interface{}, 0, len(list))
+	for _, w := range list {
+		vs = append(vs, w.Info(labels[w.config.Profession]...))
+	}
+	return vs, total, nil
+}
+
+// SetDisable 修改worker的禁用状态，配置及依赖关系保持不变，由worker进程停止或者启动实例
+func (oe *Workers) SetDisable(profession, name string, disable bool) (*WorkerInfo, error) {
+	w, err := oe.GetEmployee(profession, name)
+	if err != nil {
+		return nil, err
+	}
+	if w.config.Disable == disable {
+		return w, nil
+	}
+	w.config.Disable = disable
+	w.config.Update = eosc.Now()
+	w.info = nil
+	w.attr = nil
+	w.version = ""
+	return w, nil
+}
+
+// Select 返回租户下标签匹配的worker，profession为空时查询所有profession
+func (oe *Workers) Select(tenant, profession string, selector labels.Selector) []*WorkerInfo {
+	list := make([]*WorkerInfo, 0)
+	for _, w := range oe.data.List() {
+		if profession != "" && w.config.Profession != profession {
+			continue
+		}
+		if w.config.Tenant != tenant {
+			continue
+		}
+		if selector.Matches(w.config.Labels) {
+			list = append(list, w)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].config.Id < list[j].config.Id
+	})
+	return list
+}
+
+// Update 保存worker，name 为带租户前缀的名称，data 中的引用及变量为租户视角
 func (oe *Workers) Update(profession, name, driver, desc string, data IData) (*WorkerInfo, error) {
 	id, ok := eosc.ToWorkerId(name, profession)
 	if !ok {
@@ -77,6 +232,7 @@ func (oe *Workers) Update(profession, name, driver, desc string, data IData) (*W
 		driver = employee.config.Driver
 	}
 	body, _ := data.Encode()
+	body = oe.absoluteBody(id, profession, name, driver, body)
 	w, err := oe.set(id, profession, name, driver, desc, body)
 	if err != nil {
 		return nil, err
@@ -85,18 +241,134 @@ func (oe *Workers) Update(profession, name, driver, desc string, data IData) (*W
 	return w, nil
 
 }
+
+// Patch 将options合并到当前配置中，值为nil的字段会被删除
+func (oe *Workers) Patch(profession, name string, options map[string]interface{}) (*WorkerInfo, error) {
+	workerInfo, err := oe.GetEmployee(profession, name)
+	if err != nil {
+		return nil, err
+	}
+	current := workerInfo.tenantBody()
+
+	for k, v := range options {
+		if v != nil {
+			log.Debug("patch set:", k, "=", v)
+			current[k] = v
+		} else {
+			log.Debug("patch delete:", k)
+
+			delete(current, k)
+		}
+	}
+	description := workerInfo.config.Description
+	if v, has := options["description"]; has {
+		description, _ = v.(string)
+	}
+	data, _ := json.Marshal(current)
+	log.Debug("patch betfor:", string(workerInfo.config.Body))
+	log.Debug("patch after:", string(data))
+	oe.patched = make([]string, 0, len(options))
+	for k := range options {
+		oe.patched = append(oe.patched, k)
+	}
+	defer func() {
+		oe.patched = nil
+	}()
+	return oe.Update(profession, name, workerInfo.config.Driver, description, JsonData(data))
+}
+
+// Rename 以新名称创建worker，将所有引用方配置中的RequireId改为新id，最后删除原worker
+// 返回新建的worker以及被修改的引用方
+func (oe *Workers) Rename(profession, name, newName string) (*WorkerInfo, []*WorkerInfo, error) {
+	org, err := oe.GetEmployee(profession, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	newId, ok := eosc.ToWorkerId(newName, profession)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s@%s:invalid id", newName, profession)
+	}
+	if _, has := oe.data.GetInfo(newId); has {
+		return nil, nil, fmt.Errorf("%s %w", newId, ErrorExist)
+	}
+	oldId := org.config.Id
+
+	body := make(map[string]interface{})
+	json.Unmarshal(org.config.Body, &body)
+	if _, has := body["name"]; has {
+		// 配置中保存的是租户下的相对名称
+		_, body["name"] = eosc.SplitTenant(newName)
+	}
+	if _, has := body["id"]; has {
+		body["id"] = newId
+	}
+	data, _ := json.Marshal(body)
+	created, err := oe.set(newId, profession, newName, org.config.Driver, org.config.Description, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	created.config.Disable = org.config.Disable
+
+	dependents := append([]string(nil), oe.requireManager.RequireBy(oldId)...)
+	sort.Strings(dependents)
+	updated := make([]*WorkerInfo, 0, len(dependents))
+	for _, id := range dependents {
+		w, has := oe.data.GetInfo(id)
+		if !has {
+			continue
+		}
+		p, has := oe.professions.Get(w.config.Profession)
+		if !has {
+			return nil, nil, fmt.Errorf("%s:%w", w.config.Profession, eosc.ErrorProfessionNotExist)
+		}
+		driver, has := p.GetDriver(w.config.Driver)
+		if !has {
+			return nil, nil, fmt.Errorf("%s,%w", w.config.Driver, eosc.ErrorDriverNotExist)
+		}
+		var current interface{}
+		if err := json.Unmarshal(w.config.Body, &current); err != nil {
+			return nil, nil, fmt.Errorf("%s:%w", id, err)
+		}
+		current, changed := config.RewriteRequire(driver.ConfigType(), current, oldId, newId)
+		if !changed {
+			return nil, nil, fmt.Errorf("%s:reference to %s not found in config", id, oldId)
+		}
+		data, _ := json.Marshal(current)
+		w, err = oe.set(id, w.config.Profession, w.config.Name, w.config.Driver, w.config.Description, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%w", id, err)
+		}
+		updated = append(updated, w)
+	}
+
+	if _, err := oe.Delete(oldId); err != nil {
+		return nil, nil, fmt.Errorf("%s:%w", oldId, err)
+	}
+	return created, updated, nil
+}
+
 func (oe *Workers) rebuild(id string) error {
 	info, has := oe.data.GetInfo(id)
 	if has {
+		// 变量变更引起的重建不校验原有配置
+		oe.lenient = true
+		defer func() {
+			oe.lenient = false
+		}()
 		_, err := oe.set(id, info.config.Profession, info.config.Name, info.config.Driver, info.config.Description, info.config.Body)
 		return err
 	}
 	return nil
 
 }
-func (oe *Workers) Export() map[string][]*WorkerInfo {
+
+// Export 返回租户下的所有worker，按profession分组
+func (oe *Workers) Export(tenant string) map[string][]*WorkerInfo {
 	all := make(map[string][]*WorkerInfo)
 	for _, w := range oe.data.All() {
+		if w.config.Tenant != tenant {
+			continue
+		}
 		all[w.config.Profession] = append(all[w.config.Profession], w)
 	}
 	return all
@@ -109,6 +381,23 @@ func (oe *Workers) DeleteTest(ids ...string) (requires []string) {
 	}
 	return requires
 }
+
+// RequireByClosure 返回直接或间接依赖id的所有worker，包括id本身
+func (oe *Workers) RequireByClosure(id string) []string {
+	ids := []string{id}
+	visited := map[string]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, by := range oe.requireManager.RequireBy(ids[i]) {
+			if visited[by] {
+				continue
+			}
+			visited[by] = true
+			ids = append(ids, by)
+		}
+	}
+	return ids
+}
+
 func (oe *Workers) Delete(id string) (*WorkerInfo, error) {
 
 	worker, has := oe.data.GetInfo(id)
@@ -121,9 +410,8 @@ func (oe *Workers) Delete(id string) (*WorkerInfo, error) {
 	}
 
 	oe.data.Del(id)
-	destroy, ok := worker.worker.(eosc.IWorkerDestroy)
-	if ok {
-		destroy.Destroy()
+	if !oe.isClone {
+		destroyWorker(worker.worker)
 	}
 	oe.requireManager.Del(id)
 	oe.variables.RemoveRequire(id)
@@ -146,6 +434,10 @@ func (oe *Workers) GetEmployee(profession, name string) (*WorkerInfo, error) {
 func (oe *Workers) set(id, profession, name, driverName, desc string, body []byte) (*WorkerInfo, error) {
 
 	log.Debug("set:", id, ",", profession, ",", name, ",", driverName)
+	tenant, _ := eosc.SplitTenant(id)
+	if _, name = eosc.SplitTenant(name); strings.Contains(name, eosc.TenantSeparator) {
+		return nil, fmt.Errorf("%s:invalid name", name)
+	}
 	p, has := oe.professions.Get(profession)
 	if !has {
 		return nil, fmt.Errorf("%s:%w", profession, eosc.ErrorProfessionNotExist)
@@ -158,6 +450,14 @@ func (oe *Workers) set(id, profession, name, driverName, desc string, body []byt
 		return nil, fmt.Errorf("%s,%w", driverName, eosc.ErrorDriverNotExist)
 	}
 
+	if !oe.lenient {
+		if err := validateBody(driver.ConfigType(), body, oe.patched); err != nil {
+			return nil, err
+		}
+		if _, err := readLabels(body); err != nil {
+			return nil, err
+		}
+	}
 	conf, usedVariables, err := oe.variables.Unmarshal(body, driver.ConfigType())
 	if err != nil {
 		return nil, err
@@ -168,11 +468,19 @@ func (oe *Workers) set(id, profession, name, driverName, desc string, body []byt
 		return nil, err
 	}
 	if dc, ok := driver.(eosc.IExtenderConfigChecker); ok {
-		if e := dc.Check(conf, requires); err != nil {
+		if e := dc.Check(conf, requires); e != nil {
 			return nil, e
 		}
 	}
+	if !oe.lenient {
+		if err := oe.checkTenantRequires(id, readShared(body), getIds(requires)); err != nil {
+			return nil, err
+		}
+	}
 	wInfo, hasInfo := oe.data.GetInfo(id)
+	if oe.isClone {
+		return oe.setClone(id, tenant, profession, name, driverName, desc, body, driver, conf, requires, usedVariables, wInfo)
+	}
 	if hasInfo && wInfo.worker != nil {
 
 		e := wInfo.worker.Reset(conf, requires)
@@ -193,6 +501,7 @@ func (oe *Workers) set(id, profession, name, driverName, desc string, body []byt
 
 	if !hasInfo {
 		wInfo = NewWorkerInfo(worker, id, profession, name, driverName, desc, eosc.Now(), eosc.Now(), body, driver.ConfigType())
+		wInfo.config.Tenant = tenant
 	} else {
 		wInfo.reset(driverName, desc, body, worker, driver.ConfigType())
 	}
@@ -206,6 +515,75 @@ func (oe *Workers) set(id, profession, name, driverName, desc string, body []byt
 	return wInfo, nil
 }
 
+// setClone 副本中不复用原有的worker实例，而是重新创建，保证原数据不被修改
+func (oe *Workers) setClone(id, tenant, profession, name, driverName, desc string, body []byte, driver eosc.IExtenderDriver, conf interface{}, requires map[eosc.RequireId]eosc.IWorker, usedVariables []string, org *WorkerInfo) (*WorkerInfo, error) {
+	worker, err := driver.Create(id, name, conf, requires)
+	if err != nil {
+		return nil, err
+	}
+	create := eosc.Now()
+	if org != nil {
+		create = org.config.Create
+	}
+	wInfo := NewWorkerInfo(worker, id, profession, name, driverName, desc, create, eosc.Now(), body, driver.ConfigType())
+	wInfo.config.Tenant = tenant
+	oe.data.Set(id, wInfo)
+	oe.requireManager.Set(id, getIds(requires))
+	oe.variables.SetVariablesById(id, usedVariables)
+	return wInfo, nil
+}
+
+// setEvents 生成worker变更的事件，同时记录历史版本，副本中只生成worker事件
+func (oe *Workers) setEvents(obj *WorkerInfo) []*open_api.EventResponse {
+	eventData, _ := json.Marshal(obj.config)
+	events := []*open_api.EventResponse{{
+		Event:     eosc.EventSet,
+		Namespace: eosc.NamespaceWorker,
+		Key:       obj.config.Id,
+		Data:      eventData,
+	}}
+	if oe.isClone || oe.history == nil {
+		return events
+	}
+	if event, ok := oe.history.Append(obj); ok {
+		events = append(events, event)
+	}
+	return events
+}
+
+func (oe *Workers) delEvents(id string) []*open_api.EventResponse {
+	events := []*open_api.EventResponse{{
+		Event:     eosc.EventDel,
+		Namespace: eosc.NamespaceWorker,
+		Key:       id,
+		Data:      nil,
+	}}
+	if oe.isClone || oe.history == nil {
+		return events
+	}
+	if event, ok := oe.history.Delete(id); ok {
+		events = append(events, event)
+	}
+	return events
+}
+
+// absoluteBody 将租户视角的body转换为保存的格式，profession或者driver不存在时由set返回错误
+func (oe *Workers) absoluteBody(id, profession, name, driverName string, body []byte) []byte {
+	p, has := oe.professions.Get(profession)
+	if !has {
+		return body
+	}
+	tenant, _ := eosc.SplitTenant(id)
+	if p.Mod == eosc.ProfessionConfig_Singleton {
+		_, driverName = eosc.SplitTenant(name)
+	}
+	driver, has := p.GetDriver(driverName)
+	if !has {
+		return body
+	}
+	return absoluteBody(driver.ConfigType(), tenant, body)
+}
+
 func getIds(m map[eosc.RequireId]eosc.IWorker) []string {
 	if len(m) == 0 {
 		return nil
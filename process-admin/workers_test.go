@@ -0,0 +1,103 @@
+package process_admin
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/professions"
+	"github.com/eolinker/eosc/variable"
+)
+
+type testConfig struct {
+	Value string `json:"value"`
+}
+
+// testWorker Reset时value为reset-fail会失败，用于模拟driver在副本与当前数据上行为不一致
+type testWorker struct {
+	id        string
+	value     string
+	destroyed bool
+}
+
+func (w *testWorker) Id() string {
+	return w.id
+}
+
+func (w *testWorker) Start() error {
+	return nil
+}
+
+func (w *testWorker) Reset(conf interface{}, workers map[eosc.RequireId]eosc.IWorker) error {
+	c := conf.(*testConfig)
+	if c.Value == "reset-fail" {
+		return errors.New("reset fail")
+	}
+	w.value = c.Value
+	return nil
+}
+
+func (w *testWorker) Stop() error {
+	return nil
+}
+
+func (w *testWorker) Destroy() error {
+	w.destroyed = true
+	return nil
+}
+
+func (w *testWorker) CheckSkill(skill string) bool {
+	return true
+}
+
+type testDriver struct{}
+
+func (d *testDriver) ConfigType() reflect.Type {
+	return reflect.TypeOf(new(testConfig))
+}
+
+func (d *testDriver) Create(id, name string, v interface{}, workers map[eosc.RequireId]eosc.IWorker) (eosc.IWorker, error) {
+	c := v.(*testConfig)
+	if c.Value == "invalid" {
+		return nil, errors.New("invalid value")
+	}
+	return &testWorker{id: id, value: c.Value}, nil
+}
+
+type testFactory struct{}
+
+func (f *testFactory) Render() interface{} {
+	return nil
+}
+
+func (f *testFactory) Create(profession string, name string, label string, desc string, params map[string]interface{}) (eosc.IExtenderDriver, error) {
+	return &testDriver{}, nil
+}
+
+type testExtenders struct{}
+
+func (e *testExtenders) GetDriver(name string) (eosc.IExtenderDriverFactory, bool) {
+	return &testFactory{}, true
+}
+
+// newTestWorkers 创建只包含router专业的Workers，driver为http
+func newTestWorkers(t *testing.T, variables eosc.IVariable) *Workers {
+	t.Helper()
+	return newTestWorkersWithData(t, variables, nil)
+}
+
+func newTestWorkersWithData(t *testing.T, variables eosc.IVariable, initData map[string][]byte) *Workers {
+	t.Helper()
+	ps := professions.NewProfessions(&testExtenders{})
+	ps.Reset([]*eosc.ProfessionConfig{{
+		Name:    "router",
+		Drivers: []*eosc.DriverConfig{{Id: "test:http", Name: "http"}},
+	}})
+	if variables == nil {
+		variables = variable.NewVariables(nil)
+	}
+	ws := NewWorkers()
+	ws.Init(ps, NewWorkerDatas(initData), variables, NewWorkerHistory(nil, historyMaxRevision))
+	return ws
+}
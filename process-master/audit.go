@@ -0,0 +1,282 @@
+package process_master
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/auth"
+	"github.com/eolinker/eosc/common/dispatcher"
+	"github.com/eolinker/eosc/env"
+	"github.com/eolinker/eosc/log"
+	"github.com/eolinker/eosc/log/filelog"
+	open_api "github.com/eolinker/eosc/open-api"
+)
+
+const (
+	// auditMaxRecord etcd中保留的最大审计记录数
+	auditMaxRecord  = 10000
+	auditTimeFormat = time.RFC3339Nano
+)
+
+// AuditRecord 一次配置变更的审计记录，hash 包含上一条记录的hash，用于发现记录被篡改
+type AuditRecord struct {
+	Time       string `json:"time"`
+	RemoteAddr string `json:"remote_addr"`
+	RealIP     string `json:"real_ip,omitempty"`
+	Principal  string `json:"principal"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Event      string `json:"event"`
+	Namespace  string `json:"namespace"`
+	Key        string `json:"key"`
+	Before     string `json:"before,omitempty"`
+	After      string `json:"after,omitempty"`
+	Prev       string `json:"prev"`
+	Hash       string `json:"hash"`
+}
+
+func (r *AuditRecord) sum() string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{r.Prev, r.Time, r.RemoteAddr, r.RealIP, r.Principal, r.Method, r.Path, r.Event, r.Namespace, r.Key, r.Before, r.After}, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Auditor 为open api产生的事件生成审计记录，记录与事件在同一个事务中提交到 NamespaceAudit
+type Auditor struct {
+	locker     sync.Mutex
+	data       *dispatcher.Data
+	lastKey    string
+	lastHash   string
+	logger     *log.Logger
+	authorizer *auth.Manager
+}
+
+func NewAuditor(center dispatcher.IDispatchCenter, authorizer *auth.Manager) *Auditor {
+	a := &Auditor{
+		data:       dispatcher.NewMyData(nil),
+		logger:     newAuditLogger(),
+		authorizer: authorizer,
+	}
+	center.Register(func(e dispatcher.IEvent) error {
+		a.data.DoEvent(e)
+		return nil
+	})
+	return a
+}
+
+// newAuditLogger 审计记录单独输出到 audit.log
+func newAuditLogger() *log.Logger {
+	writer := filelog.NewFileWriteByPeriod()
+	period, _ := filelog.ParsePeriod(env.ErrorPeriod())
+	writer.Set(env.LogDir(), "audit.log", period, env.ErrorExpire())
+	writer.Open()
+	transport := log.NewTransport(writer, log.InfoLevel)
+	transport.SetFormatter(&log.LineFormatter{
+		TimestampFormat:  "2006-01-02 15:04:05",
+		CallerPrettyfier: nil,
+	})
+	logger := log.NewLogger(transport, false, "")
+	logger.SetPrefix(fmt.Sprintf("[audit-%d]", os.Getpid()))
+	return logger
+}
+
+// Audit 生成审计记录对应的事件，超过上限时删除最早的记录
+func (a *Auditor) Audit(r *http.Request, events []*open_api.EventResponse) []*open_api.EventResponse {
+	a.locker.Lock()
+	defer a.locker.Unlock()
+
+	stored, _ := a.data.GetNamespace(eosc.NamespaceAudit)
+	keys := make([]string, 0, len(stored))
+	for k := range stored {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	prevKey, prev := a.lastKey, a.lastHash
+	if len(keys) > 0 && keys[len(keys)-1] > prevKey {
+		prevKey = keys[len(keys)-1]
+		last := new(AuditRecord)
+		json.Unmarshal(stored[prevKey], last)
+		prev = last.Hash
+	}
+
+	principal := ""
+	if user, ok := auth.RequestUser(r); ok {
+		principal = user.Name
+	}
+	now := time.Now()
+	audits := make([]*open_api.EventResponse, 0, len(events)*2)
+	for i, e := range events {
+		if e.Namespace == eosc.NamespaceAudit {
+			continue
+		}
+		record := &AuditRecord{
+			Time:       now.Format(auditTimeFormat),
+			RemoteAddr: r.RemoteAddr,
+			RealIP:     r.Header.Get("X-Real-IP"),
+			Principal:  principal,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Event:      e.Event,
+			Namespace:  e.Namespace,
+			Key:        e.Key,
+			Before:     hashValue(a.data, e.Namespace, e.Key),
+			Prev:       prev,
+		}
+		if e.Event == eosc.EventSet {
+			record.After = hashBytes(e.Data)
+		}
+		record.Hash = record.sum()
+		prev = record.Hash
+
+		data, _ := json.Marshal(record)
+		audits = append(audits, &open_api.EventResponse{
+			Event:     eosc.EventSet,
+			Namespace: eosc.NamespaceAudit,
+			Key:       fmt.Sprintf("%020d-%04d", now.UnixNano(), i),
+			Data:      data,
+		})
+	}
+	if over := len(keys) + len(audits) - auditMaxRecord; over > 0 {
+		for _, k := range keys[:over] {
+			audits = append(audits, &open_api.EventResponse{
+				Event:     eosc.EventDel,
+				Namespace: eosc.NamespaceAudit,
+				Key:       k,
+			})
+		}
+	}
+	return audits
+}
+
+// Commit 事件提交成功后输出到审计日志
+func (a *Auditor) Commit(audits []*open_api.EventResponse) {
+	a.locker.Lock()
+	defer a.locker.Unlock()
+	for _, e := range audits {
+		if e.Event != eosc.EventSet {
+			continue
+		}
+		record := new(AuditRecord)
+		if err := json.Unmarshal(e.Data, record); err != nil {
+			continue
+		}
+		a.lastKey, a.lastHash = e.Key, record.Hash
+		a.logger.Logln(log.InfoLevel, string(e.Data))
+	}
+}
+
+func hashValue(data *dispatcher.Data, namespace, key string) string {
+	values, has := data.GetNamespace(namespace)
+	if !has {
+		return ""
+	}
+	v, has := values[key]
+	if !has {
+		return ""
+	}
+	return hashBytes(v)
+}
+
+func hashBytes(v []byte) string {
+	h := sha256.Sum256(v)
+	return hex.EncodeToString(h[:])
+}
+
+// verifyAuditChain 校验记录的hash以及与上一条记录的关联，records 需按key排序，最早的记录可能已被删除，不校验其prev
+func verifyAuditChain(records []*AuditRecord) error {
+	for i, record := range records {
+		if record.Hash != record.sum() {
+			return fmt.Errorf("audit record %s:hash mismatch", record.Time)
+		}
+		if i > 0 && record.Prev != records[i-1].Hash {
+			return fmt.Errorf("audit record %s:chain broken", record.Time)
+		}
+	}
+	return nil
+}
+
+// ServeHTTP GET /system/audit?since=&key=
+// since 为unix时间戳或者RFC3339格式的时间，key 为变更的key
+// 只返回用户可以访问的租户下且有读权限的记录，X-Audit-Verify 为整条记录链的校验结果
+func (a *Auditor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var since time.Time
+	if v := query.Get("since"); v != "" {
+		if t, err := strconv.ParseInt(v, 10, 64); err == nil {
+			since = time.Unix(t, 0)
+		} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+			since = t
+		} else {
+			w.Header().Set("content-type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"code":%d,"error":"invalid since:%s"}`, http.StatusBadRequest, v)
+			return
+		}
+	}
+	key := query.Get("key")
+	user, _ := auth.RequestUser(r)
+
+	chain := a.records()
+	verify := "ok"
+	if err := verifyAuditChain(chain); err != nil {
+		log.Error("verify audit:", err)
+		verify = err.Error()
+	}
+	records := make([]*AuditRecord, 0, len(chain))
+	for _, record := range chain {
+		if key != "" && record.Key != key {
+			continue
+		}
+		if !since.IsZero() {
+			t, err := time.Parse(auditTimeFormat, record.Time)
+			if err != nil || t.Before(since) {
+				continue
+			}
+		}
+		if !a.allow(user, record) {
+			continue
+		}
+		records = append(records, record)
+	}
+	w.Header().Set("content-type", "application/json")
+	w.Header().Set("X-Audit-Verify", verify)
+	json.NewEncoder(w).Encode(records)
+}
+
+// records 返回按key排序的所有审计记录
+func (a *Auditor) records() []*AuditRecord {
+	stored, _ := a.data.GetNamespace(eosc.NamespaceAudit)
+	keys := make([]string, 0, len(stored))
+	for k := range stored {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	records := make([]*AuditRecord, 0, len(keys))
+	for _, k := range keys {
+		record := new(AuditRecord)
+		if err := json.Unmarshal(stored[k], record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// allow 用户需要可以访问记录所属的租户，并且对记录的资源有读权限，未启用认证时不限制
+func (a *Auditor) allow(user *auth.User, record *AuditRecord) bool {
+	tenant, resource := watchScope(record.Namespace, record.Key)
+	if user != nil && !user.AllowTenant(tenant) {
+		return false
+	}
+	return a.authorizer.Authorize(user, http.MethodGet, resource)
+}
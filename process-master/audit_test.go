@@ -0,0 +1,78 @@
+package process_master
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/auth"
+	"github.com/eolinker/eosc/common/dispatcher"
+	open_api "github.com/eolinker/eosc/open-api"
+)
+
+// commitAudits 模拟raft提交审计事件
+func commitAudits(a *Auditor, audits []*open_api.EventResponse) {
+	all := a.data.GET()
+	if all[eosc.NamespaceAudit] == nil {
+		all[eosc.NamespaceAudit] = make(map[string][]byte)
+	}
+	for _, e := range audits {
+		all[eosc.NamespaceAudit][e.Key] = e.Data
+	}
+	a.data = dispatcher.NewMyData(all)
+}
+
+func TestAuditor_verify(t *testing.T) {
+	a := &Auditor{
+		data:       dispatcher.NewMyData(nil),
+		authorizer: auth.NewManager(dispatcher.NewDataDispatchCenter()),
+	}
+	r := httptest.NewRequest(http.MethodPut, "/api/router/demo", nil)
+	r.Header.Set(auth.HeaderPrincipal, "other")
+	r = auth.WithUser(r, &auth.User{Name: "operator"})
+	commitAudits(a, a.Audit(r, []*open_api.EventResponse{
+		{Event: eosc.EventSet, Namespace: eosc.NamespaceWorker, Key: "demo@router", Data: []byte(`{"id":"demo@router"}`)},
+		{Event: eosc.EventSet, Namespace: eosc.NamespaceWorker, Key: "acme/demo@router", Data: []byte(`{"id":"acme/demo@router"}`)},
+	}))
+	commitAudits(a, a.Audit(r, []*open_api.EventResponse{
+		{Event: eosc.EventDel, Namespace: eosc.NamespaceWorker, Key: "demo@router"},
+	}))
+
+	records := a.records()
+	if len(records) != 3 {
+		t.Fatalf("records = %d", len(records))
+	}
+	if err := verifyAuditChain(records); err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Principal != "operator" {
+		t.Errorf("principal = %s", records[0].Principal)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/audit", nil))
+	if v := w.Header().Get("X-Audit-Verify"); v != "ok" {
+		t.Errorf("X-Audit-Verify = %s", v)
+	}
+
+	// 修改记录内容
+	tampered := *records[1]
+	tampered.Key = "other@router"
+	if err := verifyAuditChain([]*AuditRecord{records[0], &tampered, records[2]}); err == nil {
+		t.Error("modified record should fail")
+	}
+	// 修改记录后重新计算hash，后一条记录的prev不再匹配
+	tampered.Hash = tampered.sum()
+	if err := verifyAuditChain([]*AuditRecord{records[0], &tampered, records[2]}); err == nil {
+		t.Error("rehashed record should break the chain")
+	}
+	// 删除中间的记录
+	if err := verifyAuditChain([]*AuditRecord{records[0], records[2]}); err == nil {
+		t.Error("removed record should break the chain")
+	}
+
+	user := &auth.User{Name: "acme", Tenants: []string{"acme"}}
+	if a.allow(user, records[0]) || !a.allow(user, records[1]) {
+		t.Error("records should be filtered by the tenants of user")
+	}
+}
@@ -17,6 +17,7 @@ type DispatcherServer struct {
 	datacenter    dispatcher.IDispatchCenter
 	ctxManager    *CtxManager
 	currentStatus bool
+	workerStatus  *workerStatusStore
 }
 
 func (d *DispatcherServer) Update(es []*extender.Status, success bool) {
@@ -27,7 +28,7 @@ func (d *DispatcherServer) Update(es []*extender.Status, success bool) {
 }
 
 func NewDispatcherServer() *DispatcherServer {
-	return &DispatcherServer{datacenter: dispatcher.NewDataDispatchCenter(), ctxManager: NewCtxManager()}
+	return &DispatcherServer{datacenter: dispatcher.NewDataDispatchCenter(), ctxManager: NewCtxManager(), workerStatus: newWorkerStatusStore()}
 }
 
 type CtxWidthCancel struct {
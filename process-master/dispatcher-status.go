@@ -0,0 +1,78 @@
+package process_master
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/eolinker/eosc/log"
+	"github.com/eolinker/eosc/service"
+)
+
+// workerStatusStore 保存各个worker进程上报的最新状态
+type workerStatusStore struct {
+	locker  sync.RWMutex
+	reports map[int32]*service.WorkerStatusReport
+	// latest 最近一次上报的worker进程，重启期间新旧进程同时存在时以最新的为准
+	latest int32
+}
+
+func newWorkerStatusStore() *workerStatusStore {
+	return &workerStatusStore{reports: make(map[int32]*service.WorkerStatusReport)}
+}
+
+func (s *workerStatusStore) set(report *service.WorkerStatusReport) {
+	s.locker.Lock()
+	s.reports[report.Pid] = report
+	s.latest = report.Pid
+	s.locker.Unlock()
+}
+
+func (s *workerStatusStore) remove(pid int32) {
+	s.locker.Lock()
+	delete(s.reports, pid)
+	if s.latest == pid {
+		s.latest = 0
+		for p := range s.reports {
+			s.latest = p
+			break
+		}
+	}
+	s.locker.Unlock()
+}
+
+func (s *workerStatusStore) list() []*service.WorkerStatus {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	report, has := s.reports[s.latest]
+	if !has {
+		return nil
+	}
+	return report.Status
+}
+
+// ReportStatus 接收worker进程上报的状态，每次上报为全量数据
+func (d *DispatcherServer) ReportStatus(server service.MasterDispatcher_ReportStatusServer) error {
+	var pid int32
+	defer func() {
+		if pid != 0 {
+			d.workerStatus.remove(pid)
+		}
+	}()
+	for {
+		report, err := server.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return server.SendAndClose(&service.EmptyRequest{})
+			}
+			log.Debug("worker status report closed: ", err)
+			return nil
+		}
+		pid = report.Pid
+		d.workerStatus.set(report)
+	}
+}
+
+func (d *DispatcherServer) WorkerStatus(ctx context.Context, request *service.EmptyRequest) (*service.WorkerStatusList, error) {
+	return &service.WorkerStatusList{Status: d.workerStatus.list()}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/eolinker/eosc"
 	"github.com/eolinker/eosc/etcd"
+	open_api "github.com/eolinker/eosc/open-api"
 	"net/url"
 )
 
@@ -12,7 +13,7 @@ type EtcdSender struct {
 }
 
 func (e *EtcdSender) Send(event string, namespace string, key string, data []byte) error {
-	dataKey := fmt.Sprintf("/%s/%s", namespace, url.PathEscape(key))
+	dataKey := toDataKey(namespace, key)
 	switch event {
 	case eosc.EventSet:
 		return e.Etcd.Put(dataKey, data)
@@ -22,6 +23,40 @@ func (e *EtcdSender) Send(event string, namespace string, key string, data []byt
 	return nil
 }
 
+// SendBatch 将一组事件在同一个etcd事务中提交，同一个key只保留最后一次操作
+func (e *EtcdSender) SendBatch(events []*open_api.EventResponse) error {
+	index := make(map[string]int, len(events))
+	ops := make([]*etcd.KVOperation, 0, len(events))
+	for _, event := range events {
+		var op *etcd.KVOperation
+		dataKey := toDataKey(event.Namespace, event.Key)
+		switch event.Event {
+		case eosc.EventSet:
+			op = &etcd.KVOperation{Key: dataKey, Value: event.Data}
+		case eosc.EventDel:
+			op = &etcd.KVOperation{Key: dataKey, Delete: true}
+		default:
+			continue
+		}
+		if i, has := index[dataKey]; has {
+			ops[i] = nil
+		}
+		index[dataKey] = len(ops)
+		ops = append(ops, op)
+	}
+	commit := ops[:0]
+	for _, op := range ops {
+		if op != nil {
+			commit = append(commit, op)
+		}
+	}
+	return e.Etcd.Batch(commit...)
+}
+
+func toDataKey(namespace, key string) string {
+	return fmt.Sprintf("/%s/%s", namespace, url.PathEscape(key))
+}
+
 func NewEtcdSender(etcd etcd.Etcd) *EtcdSender {
 	return &EtcdSender{Etcd: etcd}
 }
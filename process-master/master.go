@@ -12,6 +12,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"github.com/eolinker/eosc/auth"
 	"github.com/eolinker/eosc/etcd"
 	"github.com/eolinker/eosc/process"
 	"github.com/eolinker/eosc/process-master/extender"
@@ -85,6 +86,10 @@ type Master struct {
 	adminController  *AdminController
 	dispatcherServe  *DispatcherServer
 	adminClient      *UnixClient
+	watchServer      *WatchServer
+	authManager      *auth.Manager
+	auditor          *Auditor
+	scheduler        *Scheduler
 }
 
 type MasterHandler struct {
@@ -136,6 +141,10 @@ func (m *Master) start(handler *MasterHandler, etcdServer etcd.Etcd) error {
 	m.dispatcherServe = NewDispatcherServer()
 	extenderManager := extender.NewManager(m.ctx, extender.GenCallbackList(m.dispatcherServe, m.workerController))
 	m.dataController = NewDataController(raftService, extenderManager, m.dispatcherServe)
+	m.authManager = auth.NewManager(raftService)
+	m.watchServer = NewWatchServer(raftService, m.authManager)
+	m.auditor = NewAuditor(raftService, m.authManager)
+	m.scheduler = NewScheduler(raftService, m.authManager)
 
 	etcdServer.Watch("/", raftService)
 	etcdServer.HandlerLeader(m.adminController)
@@ -171,11 +180,21 @@ func (m *Master) Start(handler *MasterHandler) error {
 	}
 	openApiProxy := open_api.NewOpenApiProxy(NewEtcdSender(m.etcdServer), m.adminClient)
 
-	openApiMux.Handle("/system/version", handler.VersionHandler(etcdServer))
-	openApiMux.HandleFunc("/system/info", m.EtcdInfoHandler)
-	openApiMux.HandleFunc("/system/nodes", m.EtcdNodesHandler)
-	openApiMux.Handle("/", openApiProxy)
-	etcdMux.Handle("/", openApiProxy) // 转发到leader 需要具体节点，所以peer上也要绑定 open api
+	openApiProxy.SetAuditor(m.auditor)
+	// 定时变更只在leader上执行，执行时与open api请求一样经过admin
+	m.scheduler.SetHandler(NewEtcdSender(m.etcdServer), openApiProxy)
+	etcdServer.HandlerLeader(m.scheduler)
+	openApiProxy.ExcludeHandler(http.MethodGet, "/watch", m.watchServer)
+	// 所有open api都需要经过认证，未配置用户时不做限制
+	authHandler := m.authManager.Handler
+	openApiMux.Handle("/system/version", authHandler(handler.VersionHandler(etcdServer)))
+	openApiMux.Handle("/system/info", authHandler(http.HandlerFunc(m.EtcdInfoHandler)))
+	openApiMux.Handle("/system/nodes", authHandler(http.HandlerFunc(m.EtcdNodesHandler)))
+	openApiMux.Handle("/system/audit", authHandler(m.auditor))
+	openApiMux.Handle(schedulePath, authHandler(m.scheduler))
+	openApiMux.Handle(schedulePath+"/", authHandler(m.scheduler))
+	openApiMux.Handle("/", authHandler(openApiProxy))
+	etcdMux.Handle("/", authHandler(openApiProxy)) // 转发到leader 需要具体节点，所以peer上也要绑定 open api
 
 	log.Info("process-master start grpc service")
 	err = m.startService()
@@ -203,6 +222,14 @@ func (m *Master) listen(conf config.UrlConfig) (*http.ServeMux, error) {
 			return nil, err
 		}
 		tlsConf := &tls.Config{GetCertificate: cert.GetCertificate}
+		if conf.ClientCA != "" {
+			pool, err := config.LoadClientCA(conf.ClientCA, m.config.CertificateDir.Dir)
+			if err != nil {
+				return nil, err
+			}
+			tlsConf.ClientCAs = pool
+			tlsConf.ClientAuth = tls.VerifyClientCertIfGiven
+		}
 		for _, l := range ssl {
 			listener = append(listener, tls.NewListener(l, tlsConf))
 		}
@@ -306,6 +333,9 @@ func (m *Master) close() {
 	m.workerTraffic.Close()
 	m.dispatcherServe.Close()
 	m.dataController.Close()
+	if m.scheduler != nil {
+		m.scheduler.Close()
+	}
 	m.stopService()
 	log.Debug("try remove pid")
 
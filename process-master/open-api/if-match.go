@@ -0,0 +1,94 @@
+package open_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	open_api "github.com/eolinker/eosc/open-api"
+)
+
+// checkIfMatch 校验写请求的If-Match，需要在leader上执行，校验失败时已写入响应并返回false
+func (p *OpenApiProxy) checkIfMatch(w http.ResponseWriter, r *http.Request) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	switch r.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodPost:
+	default:
+		return true
+	}
+	if !isWorkerPath(r.URL.Path) {
+		return true
+	}
+
+	req, err := http.NewRequest(http.MethodGet, r.URL.Path, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	req.RequestURI = r.URL.Path
+	// 带上租户及用户信息，读取的是请求对应租户下的worker
+	req.Header = r.Header.Clone()
+	req.Header.Del("If-Match")
+	req.Header.Del("Content-Length")
+	req.Header.Set("content-type", "application/json")
+
+	buf := p.pool.Get().(*_ProxyWriterBuffer)
+	buf.Reset()
+	defer p.pool.Put(buf)
+	p.leaderHandler.ServeHTTP(buf, req)
+
+	res := new(open_api.Response)
+	if buf.statusCode != http.StatusOK || json.Unmarshal(buf.buf.Bytes(), res) != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("read current version fail"))
+		return false
+	}
+	if res.StatusCode != http.StatusOK {
+		writeError(w, http.StatusPreconditionFailed, fmt.Errorf("worker not exist"))
+		return false
+	}
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		// 不支持版本的对象不做校验
+		return true
+	}
+	if !matchETag(ifMatch, etag) {
+		w.Header().Set("ETag", etag)
+		writeError(w, http.StatusPreconditionFailed, fmt.Errorf("version not match, current is %s", etag))
+		return false
+	}
+	return true
+}
+
+// isWorkerPath 判断是否为 /api/:profession/:name 或 /t/:tenant/api/:profession/:name
+func isWorkerPath(path string) bool {
+	vs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(vs) > 2 && vs[0] == "t" {
+		vs = vs[2:]
+	}
+	return len(vs) == 3 && vs[0] == "api"
+}
+
+func matchETag(ifMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(ifMatch, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" {
+			return true
+		}
+		if strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	data, _ := json.Marshal(map[string]interface{}{"code": code, "error": err.Error()})
+	w.Write(data)
+}
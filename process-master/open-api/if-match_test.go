@@ -0,0 +1,68 @@
+package open_api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	open_api "github.com/eolinker/eosc/open-api"
+)
+
+// tenantVersionHandler 模拟admin，返回请求租户下worker的版本
+func tenantVersionHandler(principal *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get("X-Tenant")
+		if vs := strings.Split(strings.Trim(r.URL.Path, "/"), "/"); len(vs) > 1 && vs[0] == "t" {
+			tenant = vs[1]
+		}
+		if tenant == "" {
+			tenant = "default"
+		}
+		*principal = r.Header.Get("X-Eosc-Principal")
+		header := make(http.Header)
+		header.Set("ETag", `"`+tenant+`"`)
+		data, _ := json.Marshal(&open_api.Response{StatusCode: http.StatusOK, Header: header})
+		w.Write(data)
+	})
+}
+
+func TestOpenApiProxy_checkIfMatch(t *testing.T) {
+	principal := ""
+	p := NewOpenApiProxy(nil, tenantVersionHandler(&principal))
+	tests := []struct {
+		name    string
+		path    string
+		tenant  string
+		ifMatch string
+		want    bool
+	}{
+		{name: "default tenant", path: "/api/router/demo", ifMatch: `"default"`, want: true},
+		{name: "tenant header", path: "/api/router/demo", tenant: "acme", ifMatch: `"acme"`, want: true},
+		{name: "tenant header mismatch", path: "/api/router/demo", tenant: "acme", ifMatch: `"default"`, want: false},
+		{name: "tenant path", path: "/t/acme/api/router/demo", ifMatch: `"acme"`, want: true},
+		{name: "tenant path mismatch", path: "/t/acme/api/router/demo", ifMatch: `"default"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal = ""
+			r := httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader("{}"))
+			r.Header.Set("If-Match", tt.ifMatch)
+			r.Header.Set("X-Eosc-Principal", "admin")
+			if tt.tenant != "" {
+				r.Header.Set("X-Tenant", tt.tenant)
+			}
+			w := httptest.NewRecorder()
+			if got := p.checkIfMatch(w, r); got != tt.want {
+				t.Fatalf("checkIfMatch() = %v, want %v, response %s", got, tt.want, w.Body.String())
+			}
+			if !tt.want && w.Code != http.StatusPreconditionFailed {
+				t.Errorf("status = %d", w.Code)
+			}
+			if principal != "admin" {
+				t.Errorf("principal not forwarded")
+			}
+		})
+	}
+}
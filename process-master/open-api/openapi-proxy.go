@@ -18,14 +18,24 @@ var (
 
 type IRaftSender interface {
 	Send(event string, namespace string, key string, data []byte) error
+	SendBatch(events []*open_api.EventResponse) error
 	IsLeader() (bool, []string)
 }
 
+// IAuditor 为写入raft的事件生成审计记录
+type IAuditor interface {
+	Audit(r *http.Request, events []*open_api.EventResponse) []*open_api.EventResponse
+	Commit(audits []*open_api.EventResponse)
+}
+
 type OpenApiProxy struct {
 	excludeRouter *httprouter.Router
 	leaderHandler http.Handler
 	raftSender    IRaftSender
 	pool          sync.Pool
+	// writeLocker 写请求串行执行，保证If-Match校验与写入之间版本不被修改
+	writeLocker sync.Mutex
+	auditor     IAuditor
 }
 
 func NewOpenApiProxy(sender IRaftSender, leaderHandler http.Handler) *OpenApiProxy {
@@ -39,6 +49,12 @@ func NewOpenApiProxy(sender IRaftSender, leaderHandler http.Handler) *OpenApiPro
 	}
 	return p
 }
+
+// SetAuditor 设置审计，审计记录与变更事件在同一次提交中完成
+func (p *OpenApiProxy) SetAuditor(auditor IAuditor) {
+	p.auditor = auditor
+}
+
 func (p *OpenApiProxy) ExcludeHandle(method, path string, handler httprouter.Handle) {
 	for _, ph := range formatPath(path) {
 		p.excludeRouter.Handle(method, ph, handler)
@@ -105,6 +121,13 @@ func (p *OpenApiProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (p *OpenApiProxy) doProxy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		p.writeLocker.Lock()
+		defer p.writeLocker.Unlock()
+		if !p.checkIfMatch(w, r) {
+			return
+		}
+	}
 
 	buf := p.pool.Get().(*_ProxyWriterBuffer)
 	buf.Reset()
@@ -124,15 +147,24 @@ func (p *OpenApiProxy) doProxy(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, `{"code":%d,"error":"%s","re","message":"%s"}`, http.StatusInternalServerError, err.Error(), buf.buf.String())
 		return
 	}
-	if res.Event != nil {
-		for _, event := range res.Event {
-			err := p.raftSender.Send(event.Event, event.Namespace, event.Key, event.Data)
-			log.Debug("open api send:", res.Event)
-			if err != nil {
-				log.Errorf("open api raft:%v", err)
-			}
+	if len(res.Event) > 0 {
+		var audits []*open_api.EventResponse
+		if p.auditor != nil {
+			audits = p.auditor.Audit(r, res.Event)
+		}
+		// 同一个请求产生的事件在一次提交中完成，任一失败则全部不生效
+		err := p.raftSender.SendBatch(append(res.Event, audits...))
+		log.Debug("open api send:", res.Event)
+		if err == nil && p.auditor != nil {
+			p.auditor.Commit(audits)
+		}
+		if err != nil {
+			log.Errorf("open api raft:%v", err)
+			w.Header().Set("content-type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `{"code":%d,"error":"%s"}`, http.StatusInternalServerError, err.Error())
+			return
 		}
-
 	}
 	if res.Header != nil {
 		for k := range res.Header {
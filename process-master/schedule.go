@@ -0,0 +1,452 @@
+package process_master
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/auth"
+	"github.com/eolinker/eosc/common/dispatcher"
+	"github.com/eolinker/eosc/log"
+	open_api "github.com/eolinker/eosc/process-master/open-api"
+	"github.com/google/uuid"
+)
+
+const (
+	ScheduleWorker   = "worker"
+	SchedulePatch    = "patch"
+	ScheduleVariable = "variable"
+	ScheduleEnable   = "enable"
+	ScheduleDisable  = "disable"
+
+	ScheduleStatusPending  = "pending"
+	ScheduleStatusDone     = "done"
+	ScheduleStatusFailed   = "failed"
+	ScheduleStatusCanceled = "canceled"
+
+	scheduleInterval = time.Second
+	schedulePath     = "/system/schedule"
+)
+
+// ScheduledChange 定时执行的变更，保存在 NamespaceSchedule 下
+// type 为 worker(保存worker)、patch、variable(更新变量namespace)、enable、disable
+type ScheduledChange struct {
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	Profession string          `json:"profession,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Tenant     string          `json:"tenant,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	ApplyAt    string          `json:"apply_at"`
+	Status     string          `json:"status"`
+	Reason     string          `json:"reason,omitempty"`
+	Principal  string          `json:"principal,omitempty"`
+	Create     string          `json:"create"`
+	Executed   string          `json:"executed,omitempty"`
+
+	applyAt time.Time
+}
+
+// masked 返回隐藏了变量值的副本
+func (c *ScheduledChange) masked() *ScheduledChange {
+	m := *c
+	if m.Type == ScheduleVariable {
+		m.Body = maskWatchData(eosc.NamespaceVariable, m.Body)
+	}
+	return &m
+}
+
+// clearSecret 变量变更执行或取消后不再保留变量的值
+func (c *ScheduledChange) clearSecret() {
+	if c.Type == ScheduleVariable {
+		c.Body = nil
+	}
+}
+
+// request 返回执行变更时请求admin的接口
+func (c *ScheduledChange) request() (method, path string, err error) {
+	switch c.Type {
+	case ScheduleWorker, SchedulePatch, ScheduleEnable, ScheduleDisable:
+		if c.Profession == "" || c.Name == "" {
+			return "", "", fmt.Errorf("%s require profession and name", c.Type)
+		}
+		path = fmt.Sprintf("/api/%s/%s", c.Profession, c.Name)
+	case ScheduleVariable:
+		if c.Namespace == "" {
+			return "", "", fmt.Errorf("%s require namespace", c.Type)
+		}
+		return http.MethodPut, fmt.Sprintf("/variable/%s", c.Namespace), nil
+	default:
+		return "", "", fmt.Errorf("unknown type:%s", c.Type)
+	}
+	switch c.Type {
+	case ScheduleWorker:
+		return http.MethodPut, path, nil
+	case SchedulePatch:
+		return http.MethodPatch, path, nil
+	}
+	return http.MethodPost, path + "/" + c.Type, nil
+}
+
+// Scheduler 定时变更，只有leader执行，执行时与open api请求一样经过admin及审计
+type Scheduler struct {
+	locker     sync.Mutex
+	data       *dispatcher.Data
+	sender     open_api.IRaftSender
+	handler    http.Handler
+	cancel     context.CancelFunc
+	authorizer *auth.Manager
+}
+
+func NewScheduler(center dispatcher.IDispatchCenter, authorizer *auth.Manager) *Scheduler {
+	s := &Scheduler{data: dispatcher.NewMyData(nil), authorizer: authorizer}
+	center.Register(func(e dispatcher.IEvent) error {
+		s.data.DoEvent(e)
+		return nil
+	})
+	return s
+}
+
+// SetHandler 设置写入raft及执行变更的handler，需要在 LeaderChange 之前调用
+func (s *Scheduler) SetHandler(sender open_api.IRaftSender, handler http.Handler) {
+	s.locker.Lock()
+	s.sender = sender
+	s.handler = handler
+	s.locker.Unlock()
+}
+
+func (s *Scheduler) LeaderChange(isLeader bool) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if isLeader && s.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+		go s.run(ctx)
+		return
+	}
+	if !isLeader && s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+func (s *Scheduler) Close() {
+	s.LeaderChange(false)
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, c := range s.due(now) {
+				if ctx.Err() != nil {
+					return
+				}
+				s.execute(c)
+			}
+		}
+	}
+}
+
+// due 返回已到执行时间的变更，按执行时间排序
+func (s *Scheduler) due(now time.Time) []*ScheduledChange {
+	rs := make([]*ScheduledChange, 0)
+	for _, c := range s.list() {
+		if c.Status == ScheduleStatusPending && !c.applyAt.After(now) {
+			rs = append(rs, c)
+		}
+	}
+	return rs
+}
+
+func (s *Scheduler) list() []*ScheduledChange {
+	stored, _ := s.data.GetNamespace(eosc.NamespaceSchedule)
+	rs := make([]*ScheduledChange, 0, len(stored))
+	for _, v := range stored {
+		c := new(ScheduledChange)
+		if err := json.Unmarshal(v, c); err != nil {
+			continue
+		}
+		c.applyAt, _ = time.Parse(time.RFC3339, c.ApplyAt)
+		rs = append(rs, c)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if !rs[i].applyAt.Equal(rs[j].applyAt) {
+			return rs[i].applyAt.Before(rs[j].applyAt)
+		}
+		return rs[i].Id < rs[j].Id
+	})
+	return rs
+}
+
+func (s *Scheduler) get(id string) (*ScheduledChange, bool) {
+	stored, _ := s.data.GetNamespace(eosc.NamespaceSchedule)
+	v, has := stored[id]
+	if !has {
+		return nil, false
+	}
+	c := new(ScheduledChange)
+	if err := json.Unmarshal(v, c); err != nil {
+		return nil, false
+	}
+	c.applyAt, _ = time.Parse(time.RFC3339, c.ApplyAt)
+	return c, true
+}
+
+// execute 通过admin执行变更，admin未就绪时保留到下次执行，其他错误记录为失败原因
+func (s *Scheduler) execute(c *ScheduledChange) {
+	s.locker.Lock()
+	handler := s.handler
+	s.locker.Unlock()
+	if handler == nil {
+		return
+	}
+	method, path, err := c.request()
+	if err != nil {
+		s.finish(c, err.Error())
+		return
+	}
+	// 执行前按创建人当前的权限重新鉴权，用户被删除或者权限被收回后不再执行
+	user, has := s.authorizer.User(c.Principal)
+	if !s.authorizer.Authorize(user, method, auth.Resource(path)) || (has && !user.AllowTenant(c.Tenant)) {
+		s.finish(c, fmt.Sprintf("%s %s:%s", c.Principal, path, http.StatusText(http.StatusForbidden)))
+		return
+	}
+	var body []byte
+	if c.Type != ScheduleEnable && c.Type != ScheduleDisable {
+		body = c.Body
+	}
+	r, err := http.NewRequest(method, path, bytes.NewReader(body))
+	if err != nil {
+		s.finish(c, err.Error())
+		return
+	}
+	r.RequestURI = path
+	r.RemoteAddr = "scheduler"
+	r.Header.Set("content-type", "application/json")
+	if has {
+		r.Header.Set(auth.HeaderPrincipal, user.Name)
+		r = auth.WithUser(r, user)
+	}
+	if c.Tenant != "" {
+		r.Header.Set(auth.HeaderTenant, c.Tenant)
+	}
+	w := newScheduleWriter()
+	handler.ServeHTTP(w, r)
+	switch w.status {
+	case http.StatusOK:
+		s.finish(c, "")
+	case http.StatusBadGateway:
+		log.Warn("schedule ", c.Id, ": admin not ready:", w.body.String())
+	default:
+		reason := strings.TrimSpace(w.body.String())
+		if reason == "" {
+			reason = http.StatusText(w.status)
+		}
+		s.finish(c, reason)
+	}
+}
+
+func (s *Scheduler) finish(c *ScheduledChange, reason string) {
+	c.clearSecret()
+	c.Status = ScheduleStatusDone
+	if reason != "" {
+		c.Status = ScheduleStatusFailed
+		c.Reason = reason
+		log.Warn("schedule ", c.Id, " failed:", reason)
+	}
+	c.Executed = eosc.Now()
+	if err := s.save(c); err != nil {
+		log.Error("save schedule ", c.Id, ":", err)
+	}
+}
+
+func (s *Scheduler) save(c *ScheduledChange) error {
+	s.locker.Lock()
+	sender := s.sender
+	s.locker.Unlock()
+	if sender == nil {
+		return fmt.Errorf("scheduler not ready")
+	}
+	data, _ := json.Marshal(c)
+	return sender.Send(eosc.EventSet, eosc.NamespaceSchedule, c.Id, data)
+}
+
+// ServeHTTP 定时变更的管理接口
+// GET /system/schedule?status= 列表，POST /system/schedule 创建
+// GET /system/schedule/{id} 详情，DELETE /system/schedule/{id} 取消
+// 只能访问请求租户下用户对目标资源有权限的变更，变量的值不返回
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, schedulePath), "/")
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		status := r.URL.Query().Get("status")
+		rs := make([]*ScheduledChange, 0)
+		for _, c := range s.list() {
+			if (status == "" || c.Status == status) && s.allow(r, c, http.MethodGet) {
+				rs = append(rs, c.masked())
+			}
+		}
+		writeScheduleResult(w, rs)
+	case id == "" && r.Method == http.MethodPost:
+		s.create(w, r)
+	case id != "" && r.Method == http.MethodGet:
+		c, has := s.get(id)
+		if !has || !s.allow(r, c, http.MethodGet) {
+			writeScheduleError(w, http.StatusNotFound, fmt.Sprintf("schedule %s not exist", id))
+			return
+		}
+		writeScheduleResult(w, c.masked())
+	case id != "" && r.Method == http.MethodDelete:
+		s.cancelChange(w, r, id)
+	default:
+		writeScheduleError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+func (s *Scheduler) create(w http.ResponseWriter, r *http.Request) {
+	c := new(ScheduledChange)
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		writeScheduleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	applyAt, err := parseApplyAt(c.ApplyAt)
+	if err != nil {
+		writeScheduleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Type = strings.ToLower(c.Type)
+	c.Profession = strings.ToLower(c.Profession)
+	method, path, err := c.request()
+	if err != nil {
+		writeScheduleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 除了schedule的写权限，还需要有目标资源的权限
+	user, _ := auth.RequestUser(r)
+	if !s.authorizer.Authorize(user, method, auth.Resource(path)) {
+		writeScheduleError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+	if (c.Type == ScheduleWorker || c.Type == SchedulePatch || c.Type == ScheduleVariable) && len(c.Body) == 0 {
+		writeScheduleError(w, http.StatusBadRequest, fmt.Sprintf("%s require body", c.Type))
+		return
+	}
+	c.Id = uuid.NewString()
+	c.ApplyAt = applyAt.Format(time.RFC3339)
+	c.Status = ScheduleStatusPending
+	c.Reason = ""
+	c.Executed = ""
+	c.Principal = ""
+	if user != nil {
+		c.Principal = user.Name
+	}
+	// 与创建请求的租户一致，不使用请求体中的值
+	c.Tenant = auth.Tenant(r)
+	c.Create = eosc.Now()
+	if err := s.save(c); err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeScheduleResult(w, c.masked())
+}
+
+// cancelChange 只有未执行的变更可以取消，取消后保留记录，需要有目标资源的权限
+func (s *Scheduler) cancelChange(w http.ResponseWriter, r *http.Request, id string) {
+	c, has := s.get(id)
+	if !has || !s.allow(r, c, http.MethodGet) {
+		writeScheduleError(w, http.StatusNotFound, fmt.Sprintf("schedule %s not exist", id))
+		return
+	}
+	if c.Status != ScheduleStatusPending {
+		writeScheduleError(w, http.StatusConflict, fmt.Sprintf("schedule %s is %s", id, c.Status))
+		return
+	}
+	if method, _, err := c.request(); err == nil && !s.allow(r, c, method) {
+		writeScheduleError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+	c.clearSecret()
+	c.Status = ScheduleStatusCanceled
+	if err := s.save(c); err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeScheduleResult(w, c.masked())
+}
+
+// allow 变更需要属于请求的租户，并且用户对目标资源有method的权限
+func (s *Scheduler) allow(r *http.Request, c *ScheduledChange, method string) bool {
+	if c.Tenant != auth.Tenant(r) {
+		return false
+	}
+	_, path, err := c.request()
+	if err != nil {
+		return true
+	}
+	user, _ := auth.RequestUser(r)
+	return s.authorizer.Authorize(user, method, auth.Resource(path))
+}
+
+// parseApplyAt apply_at 为RFC3339格式的时间或者unix时间戳
+func parseApplyAt(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, fmt.Errorf("require apply_at")
+	}
+	if t, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(t, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid apply_at:%s", v)
+	}
+	return t, nil
+}
+
+func writeScheduleResult(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeScheduleError(w http.ResponseWriter, code int, msg string) {
+	data, _ := json.Marshal(map[string]interface{}{"code": code, "error": msg})
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// scheduleWriter 记录执行变更时admin的返回
+type scheduleWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newScheduleWriter() *scheduleWriter {
+	return &scheduleWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *scheduleWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *scheduleWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *scheduleWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+}
@@ -0,0 +1,338 @@
+package process_master
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/auth"
+	"github.com/eolinker/eosc/common/dispatcher"
+	"github.com/eolinker/eosc/log"
+	"github.com/eolinker/eosc/variable"
+	"github.com/fasthttp/websocket"
+)
+
+const (
+	// watchBufferSize 保留的最近事件数，断线重连时可以从中补发
+	watchBufferSize = 1024
+	watchHeartbeat  = time.Second * 30
+
+	WatchEventReset = "reset"
+)
+
+// WatchEvent 推送给客户端的事件，token 用于断线后续传
+type WatchEvent struct {
+	Token     string          `json:"token"`
+	Event     string          `json:"event"`
+	Namespace string          `json:"namespace,omitempty"`
+	Key       string          `json:"key,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+
+	seq uint64
+}
+
+type watchFilter struct {
+	namespace  string
+	profession string
+	// tenant 请求的租户，只推送该租户下的数据
+	tenant string
+	// allow 判断用户是否可以读取资源
+	allow func(resource string) bool
+}
+
+func (f *watchFilter) match(namespace, key string) bool {
+	if namespace == eosc.NamespaceAuth {
+		// 认证数据包含密钥，不对外推送
+		return false
+	}
+	if f.namespace != "" && f.namespace != namespace {
+		return false
+	}
+	tenant, resource := watchScope(namespace, key)
+	if tenant != f.tenant || !f.allow(resource) {
+		return false
+	}
+	if f.profession == "" {
+		return true
+	}
+	if namespace == eosc.NamespaceProfession {
+		return strings.EqualFold(key, f.profession)
+	}
+	profession, _, success := eosc.SplitWorkerId(key)
+	return success && strings.EqualFold(profession, f.profession)
+}
+
+// watchScope 返回数据所属的租户以及鉴权使用的资源名，与admin接口的租户及 auth.Resource 一致
+func watchScope(namespace, key string) (tenant string, resource string) {
+	switch namespace {
+	case eosc.NamespaceWorker, eosc.NamespaceHistory:
+		profession, name, _ := eosc.SplitWorkerId(key)
+		tenant, _ = eosc.SplitTenant(name)
+		return tenant, profession
+	case eosc.NamespaceVariable:
+		if strings.Contains(key, eosc.TenantSeparator) {
+			tenant, _ = eosc.SplitTenant(key)
+		}
+		return tenant, namespace
+	case eosc.NamespaceCluster, eosc.NamespaceAudit, eosc.NamespaceSchedule:
+		return "", "system"
+	}
+	return "", namespace
+}
+
+// maskWatchData 变量的值不对外推送，只保留变量名
+func maskWatchData(namespace string, data []byte) []byte {
+	if namespace != eosc.NamespaceVariable || len(data) == 0 {
+		return data
+	}
+	variables := make(map[string]string)
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil
+	}
+	for k := range variables {
+		variables[k] = variable.MaskedValue
+	}
+	d, _ := json.Marshal(variables)
+	return d
+}
+
+// WatchServer 将raft事件通过SSE或WebSocket推送给外部客户端
+type WatchServer struct {
+	locker sync.RWMutex
+	epoch  string
+	seq    uint64
+	events []*WatchEvent
+	data   *dispatcher.Data
+	notify chan struct{}
+
+	upgrader   websocket.Upgrader
+	authorizer *auth.Manager
+}
+
+func NewWatchServer(center dispatcher.IDispatchCenter, authorizer *auth.Manager) *WatchServer {
+	ws := &WatchServer{
+		authorizer: authorizer,
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		events:     make([]*WatchEvent, 0, watchBufferSize),
+		data:       dispatcher.NewMyData(nil),
+		notify:     make(chan struct{}),
+		// 使用默认的CheckOrigin，Origin与Host不一致的浏览器跨域请求会被拒绝，没有Origin的客户端不受影响
+		upgrader: websocket.Upgrader{},
+	}
+	center.Register(ws.doEvent)
+	return ws
+}
+
+func (ws *WatchServer) doEvent(e dispatcher.IEvent) error {
+	ws.locker.Lock()
+	defer ws.locker.Unlock()
+	ws.data.DoEvent(e)
+
+	switch e.Event() {
+	case eosc.EventInit, eosc.EventReset:
+		// 全量数据变更后，之前的事件不能再用于续传，切换epoch让客户端重新获取全量数据
+		ws.epoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+		ws.events = ws.events[:0]
+	default:
+		ws.seq++
+		event := &WatchEvent{
+			Token:     ws.token(ws.seq),
+			Event:     e.Event(),
+			Namespace: e.Namespace(),
+			Key:       e.Key(),
+			Data:      toRawMessage(maskWatchData(e.Namespace(), e.Data())),
+			seq:       ws.seq,
+		}
+		if len(ws.events) >= watchBufferSize {
+			ws.events = append(ws.events[:0], ws.events[1:]...)
+		}
+		ws.events = append(ws.events, event)
+	}
+	close(ws.notify)
+	ws.notify = make(chan struct{})
+	return nil
+}
+
+func (ws *WatchServer) token(seq uint64) string {
+	return fmt.Sprintf("%s-%d", ws.epoch, seq)
+}
+
+// read 返回token之后的事件，token无效或事件已经被丢弃时返回全量数据
+func (ws *WatchServer) read(token string, filter *watchFilter) ([]*WatchEvent, string, <-chan struct{}) {
+	ws.locker.RLock()
+	defer ws.locker.RUnlock()
+
+	seq, ok := ws.parseToken(token)
+	if ok && len(ws.events) > 0 && seq+1 < ws.events[0].seq {
+		ok = false
+	}
+	if !ok {
+		return []*WatchEvent{ws.snapshot(filter)}, ws.token(ws.seq), ws.notify
+	}
+	rs := make([]*WatchEvent, 0)
+	for _, e := range ws.events {
+		if e.seq > seq && filter.match(e.Namespace, e.Key) {
+			rs = append(rs, e)
+		}
+	}
+	return rs, ws.token(ws.seq), ws.notify
+}
+
+func (ws *WatchServer) parseToken(token string) (uint64, bool) {
+	i := strings.LastIndex(token, "-")
+	if i < 0 || token[:i] != ws.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(token[i+1:], 10, 64)
+	if err != nil || seq > ws.seq {
+		return 0, false
+	}
+	return seq, true
+}
+
+func (ws *WatchServer) snapshot(filter *watchFilter) *WatchEvent {
+	all := make(map[string]map[string]json.RawMessage)
+	for namespace, values := range ws.data.GET() {
+		for key, value := range values {
+			if !filter.match(namespace, key) {
+				continue
+			}
+			if all[namespace] == nil {
+				all[namespace] = make(map[string]json.RawMessage)
+			}
+			all[namespace][key] = toRawMessage(maskWatchData(namespace, value))
+		}
+	}
+	data, _ := json.Marshal(all)
+	return &WatchEvent{
+		Token: ws.token(ws.seq),
+		Event: WatchEventReset,
+		Data:  data,
+		seq:   ws.seq,
+	}
+}
+
+func toRawMessage(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	if json.Valid(data) {
+		return data
+	}
+	d, _ := json.Marshal(string(data))
+	return d
+}
+
+// ServeHTTP GET /watch?namespace=worker&profession=router&token=
+// 默认使用SSE推送，请求为WebSocket升级时使用WebSocket，token也可以通过Last-Event-ID传入
+// 只推送请求租户下用户有读权限的数据，变量的值不推送
+func (ws *WatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	user, _ := auth.RequestUser(r)
+	filter := &watchFilter{
+		namespace:  query.Get("namespace"),
+		profession: query.Get("profession"),
+		tenant:     auth.Tenant(r),
+		allow: func(resource string) bool {
+			return ws.authorizer.Authorize(user, http.MethodGet, resource)
+		},
+	}
+	token := query.Get("token")
+	if token == "" {
+		token = r.Header.Get("Last-Event-ID")
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		ws.serveWebsocket(w, r, filter, token)
+		return
+	}
+	ws.serveSSE(w, r, filter, token)
+}
+
+func (ws *WatchServer) serveSSE(w http.ResponseWriter, r *http.Request, filter *watchFilter, token string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ws.loop(r.Context(), filter, token, func(events []*WatchEvent) error {
+		for _, e := range events {
+			data, _ := json.Marshal(e)
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.Token, e.Event, data); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+		return nil
+	}, func() error {
+		_, err := fmt.Fprint(w, ": ping\n\n")
+		flusher.Flush()
+		return err
+	})
+}
+
+func (ws *WatchServer) serveWebsocket(w http.ResponseWriter, r *http.Request, filter *watchFilter, token string) {
+	conn, err := ws.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn("watch upgrade websocket:", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		// 客户端只需要接收，读取用于感知连接关闭
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ws.loop(ctx, filter, token, func(events []*WatchEvent) error {
+		for _, e := range events {
+			if err := conn.WriteJSON(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second*5))
+	})
+}
+
+func (ws *WatchServer) loop(ctx context.Context, filter *watchFilter, token string, send func(events []*WatchEvent) error, heartbeat func() error) {
+	ticker := time.NewTicker(watchHeartbeat)
+	defer ticker.Stop()
+	for {
+		events, next, notify := ws.read(token, filter)
+		token = next
+		if len(events) > 0 {
+			if err := send(events); err != nil {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-notify:
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -42,7 +42,7 @@ func (ws *WorkerServer) setEvent(namespace string, key string, data []byte) erro
 				return err
 			}
 
-			return ws.workers.Set(w.Id, w.Profession, w.Name, w.Driver, w.Body, ws.variableManager)
+			return ws.workers.Set(w.Id, w.Profession, w.Name, w.Driver, w.Body, w.Disable, ws.variableManager)
 		}
 	case eosc.NamespaceVariable:
 		{
@@ -54,22 +54,25 @@ func (ws *WorkerServer) setEvent(namespace string, key string, data []byte) erro
 
 			wids, clone, err := ws.variableManager.Check(key, tmp)
 			if err != nil {
-				return err
-
+				if !errors.Is(err, variable.ErrorVariableRequire) {
+					return err
+				}
+				// admin强制删除了被引用的变量，引用的worker保持当前配置
+				log.Warn("force set variable:", err)
 			}
 			ws.variableManager.SetByNamespace(key, tmp)
 			for _, id := range wids {
-				profession, _, success := eosc.SplitWorkerId(id)
+				profession, name, success := eosc.SplitWorkerId(id)
 				if !success {
 					continue
 				}
 				if profession == "setting" {
-					ws.settings.Update(id, clone)
+					ws.settings.Update(name, clone)
 				} else {
 					ws.workers.Update(id, clone)
 				}
 			}
-			return err
+			return nil
 		}
 	default:
 		return nil
@@ -90,7 +93,7 @@ func (ws *WorkerServer) delEvent(namespace string, key string) error {
 		}
 	case eosc.NamespaceVariable:
 		{
-			return nil
+			return ws.variableManager.SetByNamespace(key, nil)
 		}
 	default:
 		return errors.New(fmt.Sprintf("namespace %s is not existed.", namespace))
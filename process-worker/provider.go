@@ -0,0 +1,45 @@
+package process_worker
+
+import (
+	"fmt"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/log"
+	"github.com/eolinker/eosc/variable"
+)
+
+// watchProviders 监听外部变量来源，变量变化后重新生成引用了变量的worker
+func (ws *WorkerServer) watchProviders() {
+	for namespace, p := range variable.Providers() {
+		w, ok := p.(variable.IWatchProvider)
+		if !ok {
+			continue
+		}
+		namespace := namespace
+		go w.Watch(ws.ctx, func(keys []string) {
+			ws.providerChanged(namespace, keys)
+		})
+	}
+}
+
+func (ws *WorkerServer) providerChanged(namespace string, keys []string) {
+	ws.eventLock.Lock()
+	defer ws.eventLock.Unlock()
+	for _, key := range keys {
+		for _, id := range ws.variableManager.GetIdsByVariable(fmt.Sprintf("%s@%s", key, namespace)) {
+			profession, name, success := eosc.SplitWorkerId(id)
+			if !success {
+				continue
+			}
+			var err error
+			if profession == "setting" {
+				err = ws.settings.Update(name, ws.variableManager)
+			} else {
+				err = ws.workers.Update(id, ws.variableManager)
+			}
+			if err != nil {
+				log.Errorf("update %s by variable %s@%s:%s", id, key, namespace, err)
+			}
+		}
+	}
+}
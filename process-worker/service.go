@@ -37,6 +37,8 @@ type WorkerServer struct {
 	masterPid         int
 	onceInit          sync.Once
 	initHandler       []func()
+	// eventLock 串行处理master的事件和外部变量的变化
+	eventLock sync.Mutex
 }
 
 func NewWorkerServer(masterPid int, extends extends.IExtenderRegister, initHandlers ...func()) (*WorkerServer, error) {
@@ -56,6 +58,8 @@ func NewWorkerServer(masterPid int, extends extends.IExtenderRegister, initHandl
 	var iw eosc.IWorkers = ws.workers
 	bean.Injection(&iw)
 	ws.listenMaster()
+	ws.watchProviders()
+	go ws.reportStatus()
 	return ws, nil
 }
 
@@ -126,13 +130,13 @@ func (ws *WorkerServer) listen(conn *grpc.ClientConn, c service.MasterDispatcher
 			return
 		}
 		log.Debug("recv:", event.String())
+		ws.eventLock.Lock()
 		switch event.Command {
 		case eosc.EventInit, eosc.EventReset:
 			{
 				err := ws.resetEvent(event.Data)
 				if err != nil {
 					log.Error("reset server error: ", err)
-					continue
 				}
 			}
 		case eosc.EventSet:
@@ -144,6 +148,7 @@ func (ws *WorkerServer) listen(conn *grpc.ClientConn, c service.MasterDispatcher
 				ws.delEvent(event.Namespace, event.Key)
 			}
 		}
+		ws.eventLock.Unlock()
 	}
 	log.Debug("stop listen")
 }
@@ -0,0 +1,68 @@
+package process_worker
+
+import (
+	"os"
+	"time"
+
+	"github.com/eolinker/eosc"
+	grpc_unixsocket "github.com/eolinker/eosc/grpc-unixsocket"
+	"github.com/eolinker/eosc/log"
+	"github.com/eolinker/eosc/process-worker/workers"
+	"github.com/eolinker/eosc/service"
+)
+
+const statusRetryInterval = 5 * time.Second
+
+// reportStatus 将worker运行状态上报给master，每次状态变更时发送全量数据
+func (ws *WorkerServer) reportStatus() {
+	for {
+		err := ws.doReportStatus()
+		if err != nil {
+			log.Warn("report worker status: ", err)
+		}
+		select {
+		case <-ws.ctx.Done():
+			return
+		case <-time.After(statusRetryInterval):
+		}
+	}
+}
+
+func (ws *WorkerServer) doReportStatus() error {
+	conn, err := grpc_unixsocket.Connect(service.ServerAddr(ws.masterPid, eosc.ProcessMaster))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client, err := service.NewMasterDispatcherClient(conn).ReportStatus(ws.ctx)
+	if err != nil {
+		return err
+	}
+	defer client.CloseSend()
+	pid := int32(os.Getpid())
+	for {
+		err = client.Send(&service.WorkerStatusReport{Pid: pid, Status: toServiceStatus(ws.workers.Status())})
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ws.ctx.Done():
+			return nil
+		case <-ws.workers.StatusChanged():
+		}
+	}
+}
+
+func toServiceStatus(list []*workers.Status) []*service.WorkerStatus {
+	rs := make([]*service.WorkerStatus, 0, len(list))
+	for _, s := range list {
+		rs = append(rs, &service.WorkerStatus{
+			Id:         s.Id,
+			State:      s.State,
+			Error:      s.Error,
+			ResetTime:  s.ResetTime.UnixMilli(),
+			UpdateTime: s.UpdateTime.UnixMilli(),
+		})
+	}
+	return rs
+}
@@ -0,0 +1,121 @@
+package workers
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eolinker/eosc"
+)
+
+const (
+	StatusRunning = "running"
+	StatusFailed  = "failed"
+	// StatusPending 依赖的worker不存在，等待依赖创建
+	StatusPending = "pending"
+	// StatusDisabled worker被禁用，实例已停止
+	StatusDisabled = "disabled"
+)
+
+// Status worker在当前worker进程中的运行状态
+type Status struct {
+	Id         string
+	State      string
+	Error      string
+	ResetTime  time.Time
+	UpdateTime time.Time
+}
+
+type statusTable struct {
+	locker sync.RWMutex
+	data   map[string]*Status
+	notify chan struct{}
+}
+
+func newStatusTable() *statusTable {
+	return &statusTable{
+		data:   make(map[string]*Status),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// set 根据创建或者重置的结果记录状态，err为nil时表示worker正常运行
+func (s *statusTable) set(id string, err error) {
+	s.locker.Lock()
+	now := time.Now()
+	st, has := s.data[id]
+	if !has {
+		st = &Status{Id: id}
+		s.data[id] = st
+	}
+	st.UpdateTime = now
+	switch {
+	case err == nil:
+		st.State = StatusRunning
+		st.Error = ""
+		st.ResetTime = now
+	case errors.Is(err, eosc.ErrorWorkerNotExits) || errors.Is(err, eosc.ErrorRequire):
+		st.State = StatusPending
+		st.Error = err.Error()
+	default:
+		st.State = StatusFailed
+		st.Error = err.Error()
+	}
+	s.locker.Unlock()
+	s.changed()
+}
+
+func (s *statusTable) disable(id string) {
+	s.locker.Lock()
+	st, has := s.data[id]
+	if !has {
+		st = &Status{Id: id}
+		s.data[id] = st
+	}
+	st.State = StatusDisabled
+	st.Error = ""
+	st.UpdateTime = time.Now()
+	s.locker.Unlock()
+	s.changed()
+}
+
+func (s *statusTable) del(id string) {
+	s.locker.Lock()
+	delete(s.data, id)
+	s.locker.Unlock()
+	s.changed()
+}
+
+// keep 只保留ids中的状态
+func (s *statusTable) keep(ids map[string]bool) {
+	s.locker.Lock()
+	for id := range s.data {
+		if !ids[id] {
+			delete(s.data, id)
+		}
+	}
+	s.locker.Unlock()
+	s.changed()
+}
+
+func (s *statusTable) list() []*Status {
+	s.locker.RLock()
+	rs := make([]*Status, 0, len(s.data))
+	for _, st := range s.data {
+		c := *st
+		rs = append(rs, &c)
+	}
+	s.locker.RUnlock()
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].Id < rs[j].Id
+	})
+	return rs
+}
+
+func (s *statusTable) changed() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
@@ -15,9 +15,11 @@ import (
 type IWorkers interface {
 	eosc.IWorkers
 	Del(id string) error
-	Set(id, profession, name, driverName string, body []byte, variable eosc.IVariable) error
+	Set(id, profession, name, driverName string, body []byte, disable bool, variable eosc.IVariable) error
 	Update(id string, variable eosc.IVariable) error
 	Reset(wdl []*eosc.WorkerConfig, variable eosc.IVariable) error
+	Status() []*Status
+	StatusChanged() <-chan struct{}
 }
 
 type ConfigCache struct {
@@ -25,6 +27,9 @@ type ConfigCache struct {
 	name       string
 	driver     string
 	config     []byte
+	disable    bool
+	// requires 配置中引用的worker，包括被禁用而跳过的
+	requires []string
 }
 
 var _ IWorkers = (*Workers)(nil)
@@ -35,6 +40,17 @@ type Workers struct {
 	variables   eosc.IVariable
 	data        *WorkerDatas
 	configs     map[string]*ConfigCache
+	status      *statusTable
+}
+
+// Status 返回所有worker的运行状态
+func (wm *Workers) Status() []*Status {
+	return wm.status.list()
+}
+
+// StatusChanged 状态变更时通知
+func (wm *Workers) StatusChanged() <-chan struct{} {
+	return wm.status.notify
 }
 
 func (wm *Workers) Update(id string, variable eosc.IVariable) error {
@@ -44,7 +60,7 @@ func (wm *Workers) Update(id string, variable eosc.IVariable) error {
 	if !has {
 		return nil
 	}
-	return wm.set(id, con.profession, con.name, con.driver, con.config, variable)
+	return wm.set(id, con.profession, con.name, con.driver, con.config, con.disable, variable)
 }
 
 func (wm *Workers) Del(id string) error {
@@ -53,6 +69,9 @@ func (wm *Workers) Del(id string) error {
 
 	worker, has := wm.data.Get(id)
 	if !has {
+		// 因依赖被禁用而未创建的worker只保存了配置
+		delete(wm.configs, id)
+		wm.status.del(id)
 		return eosc.ErrorWorkerNotExits
 	}
 
@@ -67,6 +86,7 @@ func (wm *Workers) Del(id string) error {
 	}
 	delete(wm.configs, id)
 	wm.variables.RemoveRequire(id)
+	wm.status.del(id)
 	return nil
 }
 
@@ -84,6 +104,7 @@ func NewWorkerManager(profession professions.IProfessions) *Workers {
 		locker:      sync.Mutex{},
 		data:        NewTypedWorkers(),
 		configs:     make(map[string]*ConfigCache),
+		status:      newStatusTable(),
 	}
 }
 
@@ -99,6 +120,7 @@ func (wm *Workers) Reset(wdl []*eosc.WorkerConfig, variable eosc.IVariable) erro
 	wm.locker.Lock()
 	defer wm.locker.Unlock()
 	wm.variables = variable
+	oldConfigs := wm.configs
 	wm.configs = make(map[string]*ConfigCache)
 	olddata := wm.data
 	wm.data = NewTypedWorkers()
@@ -110,9 +132,13 @@ func (wm *Workers) Reset(wdl []*eosc.WorkerConfig, variable eosc.IVariable) erro
 			old, has := olddata.Del(wd.Id)
 			if has {
 				wm.data.Set(wd.Id, old)
+				if c, has := oldConfigs[wd.Id]; has {
+					// 保留原有的禁用状态，用于判断是否需要重新启动
+					wm.configs[wd.Id] = c
+				}
 			}
 			log.Debug("init set:", wd.Id, " ", wd.Profession, " ", wd.Name, " ", wd.Driver, " ", string(wd.Body))
-			if err := wm.set(wd.Id, wd.Profession, wd.Name, wd.Driver, wd.Body, variable); err != nil {
+			if err := wm.set(wd.Id, wd.Profession, wd.Name, wd.Driver, wd.Body, wd.Disable, variable); err != nil {
 				log.Error("init set worker: ", err)
 				continue
 			}
@@ -122,17 +148,73 @@ func (wm *Workers) Reset(wdl []*eosc.WorkerConfig, variable eosc.IVariable) erro
 		variable.RemoveRequire(ov.Id())
 		ov.Stop()
 	}
+	ids := make(map[string]bool, len(wdl))
+	for _, wd := range wdl {
+		ids[wd.Id] = true
+	}
+	wm.status.keep(ids)
 	return nil
 }
 
-func (wm *Workers) Set(id, profession, name, driverName string, body []byte, variable eosc.IVariable) error {
+func (wm *Workers) Set(id, profession, name, driverName string, body []byte, disable bool, variable eosc.IVariable) error {
 	wm.locker.Lock()
 	defer wm.locker.Unlock()
 
-	return wm.set(id, profession, name, driverName, body, variable)
+	changed := wm.isDisabled(id) != disable
+	err := wm.set(id, profession, name, driverName, body, disable, variable)
+	if err == nil && changed {
+		wm.resetDependents(id, variable)
+	}
+	return err
+}
+
+// set 创建或者重置worker，并记录运行状态
+func (wm *Workers) set(id, profession, name, driverName string, body []byte, disable bool, variable eosc.IVariable) error {
+	err := wm.setWorker(id, profession, name, driverName, body, disable, variable)
+	if err == nil && disable {
+		wm.status.disable(id)
+		return nil
+	}
+	wm.status.set(id, err)
+	return err
+}
+
+func (wm *Workers) isDisabled(id string) bool {
+	c, has := wm.configs[id]
+	return has && c.disable
+}
+
+// resetDependents 禁用状态变更后重新设置引用了该worker的worker
+func (wm *Workers) resetDependents(id string, variable eosc.IVariable) {
+	for did, c := range wm.configs {
+		for _, rid := range c.requires {
+			if rid != id {
+				continue
+			}
+			if err := wm.set(did, c.profession, c.name, c.driver, c.config, c.disable, variable); err != nil {
+				log.Warn("reset dependent ", did, " of ", id, ": ", err)
+			}
+			break
+		}
+	}
+}
+
+// checkDisabled 检查依赖中被禁用的worker，driver实现了 IExtenderDisabledChecker 并允许跳过时从requires中移除
+func (wm *Workers) checkDisabled(driver eosc.IExtenderDriver, requires map[eosc.RequireId]eosc.IWorker) error {
+	for rid := range requires {
+		if !wm.isDisabled(string(rid)) {
+			continue
+		}
+		if dc, ok := driver.(eosc.IExtenderDisabledChecker); ok && dc.SkipDisabled() {
+			delete(requires, rid)
+			continue
+		}
+		return fmt.Errorf("%s:%w", rid, eosc.ErrorWorkerDisabled)
+	}
+	return nil
 }
 
-func (wm *Workers) set(id, profession, name, driverName string, body []byte, variable eosc.IVariable) error {
+func (wm *Workers) setWorker(id, profession, name, driverName string, body []byte, disable bool, variable eosc.IVariable) error {
 	log.Debug("set:", id, ",", profession, ",", name, ",", driverName)
 	p, has := wm.professions.Get(profession)
 	if !has {
@@ -151,24 +233,53 @@ func (wm *Workers) set(id, profession, name, driverName string, body []byte, var
 	if err != nil {
 		return err
 	}
+	cache := &ConfigCache{
+		profession: profession,
+		name:       name,
+		driver:     driverName,
+		config:     body,
+		disable:    disable,
+		requires:   make([]string, 0, len(requires)),
+	}
+	for rid := range requires {
+		cache.requires = append(cache.requires, string(rid))
+	}
+	if err := wm.checkDisabled(driver, requires); err != nil {
+		if _, has := wm.data.Get(id); !has {
+			// 保存配置，依赖启用后重新创建
+			wm.configs[id] = cache
+		}
+		return err
+	}
 	if dc, ok := driver.(eosc.IExtenderConfigChecker); ok {
-		if e := dc.Check(conf, requires); err != nil {
+		if e := dc.Check(conf, requires); e != nil {
 			return e
 		}
 	}
+	wasDisabled := wm.isDisabled(id)
 	o, has := wm.data.Get(id)
 	if has {
-
+		if disable {
+			// 禁用时只停止实例，保留配置及依赖关系，启用时再重置
+			if !wasDisabled {
+				if e := o.Stop(); e != nil {
+					log.Warn("worker-data stop disabled worker:", e)
+				}
+			}
+			wm.variables.SetVariablesById(id, useVariables)
+			wm.configs[id] = cache
+			return nil
+		}
 		e := o.Reset(conf, requires)
 		if e != nil {
 			return e
 		}
 		wm.variables.SetVariablesById(id, useVariables)
-		wm.configs[id] = &ConfigCache{
-			profession: profession,
-			name:       name,
-			driver:     driverName,
-			config:     body,
+		wm.configs[id] = cache
+		if wasDisabled {
+			if e := o.Start(); e != nil {
+				return fmt.Errorf("worker start:%w", e)
+			}
 		}
 		return nil
 	}
@@ -178,21 +289,23 @@ func (wm *Workers) set(id, profession, name, driverName string, body []byte, var
 		log.Warn("worker-data set worker create:", err)
 		return err
 	}
-	// start
-	e := worker.Start()
-	if e != nil {
-		log.Warn("worker-data set worker start:", e)
+	// start，被禁用的worker只创建不启动
+	var startErr error
+	if !disable {
+		startErr = worker.Start()
+		if startErr != nil {
+			log.Warn("worker-data set worker start:", startErr)
+		}
 	}
 
 	// store
 	wm.data.Set(id, worker)
 	wm.variables.SetVariablesById(id, useVariables)
-	wm.configs[id] = &ConfigCache{
-		profession: profession,
-		name:       name,
-		driver:     driverName,
-		config:     body,
-	}
+	wm.configs[id] = cache
 	log.Debug("worker-data set worker done:", id)
+	if startErr != nil {
+		// worker 已经保存，启动失败时返回错误用于记录状态
+		return fmt.Errorf("worker start:%w", startErr)
+	}
 	return nil
 }
@@ -23,6 +23,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MasterDispatcherClient interface {
 	Listen(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (MasterDispatcher_ListenClient, error)
+	ReportStatus(ctx context.Context, opts ...grpc.CallOption) (MasterDispatcher_ReportStatusClient, error)
+	WorkerStatus(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*WorkerStatusList, error)
 }
 
 type masterDispatcherClient struct {
@@ -65,11 +67,56 @@ func (x *masterDispatcherListenClient) Recv() (*Event, error) {
 	return m, nil
 }
 
+func (c *masterDispatcherClient) ReportStatus(ctx context.Context, opts ...grpc.CallOption) (MasterDispatcher_ReportStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MasterDispatcher_ServiceDesc.Streams[1], "/service.MasterDispatcher/ReportStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &masterDispatcherReportStatusClient{stream}
+	return x, nil
+}
+
+type MasterDispatcher_ReportStatusClient interface {
+	Send(*WorkerStatusReport) error
+	CloseAndRecv() (*EmptyRequest, error)
+	grpc.ClientStream
+}
+
+type masterDispatcherReportStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *masterDispatcherReportStatusClient) Send(m *WorkerStatusReport) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *masterDispatcherReportStatusClient) CloseAndRecv() (*EmptyRequest, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(EmptyRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *masterDispatcherClient) WorkerStatus(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*WorkerStatusList, error) {
+	out := new(WorkerStatusList)
+	err := c.cc.Invoke(ctx, "/service.MasterDispatcher/WorkerStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MasterDispatcherServer is the server API for MasterDispatcher service.
 // All implementations must embed UnimplementedMasterDispatcherServer
 // for forward compatibility
 type MasterDispatcherServer interface {
 	Listen(*EmptyRequest, MasterDispatcher_ListenServer) error
+	ReportStatus(MasterDispatcher_ReportStatusServer) error
+	WorkerStatus(context.Context, *EmptyRequest) (*WorkerStatusList, error)
 	mustEmbedUnimplementedMasterDispatcherServer()
 }
 
@@ -80,6 +127,12 @@ type UnimplementedMasterDispatcherServer struct {
 func (UnimplementedMasterDispatcherServer) Listen(*EmptyRequest, MasterDispatcher_ListenServer) error {
 	return status.Errorf(codes.Unimplemented, "method Listen not implemented")
 }
+func (UnimplementedMasterDispatcherServer) ReportStatus(MasterDispatcher_ReportStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReportStatus not implemented")
+}
+func (UnimplementedMasterDispatcherServer) WorkerStatus(context.Context, *EmptyRequest) (*WorkerStatusList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WorkerStatus not implemented")
+}
 func (UnimplementedMasterDispatcherServer) mustEmbedUnimplementedMasterDispatcherServer() {}
 
 // UnsafeMasterDispatcherServer may be embedded to opt out of forward compatibility for this service.
@@ -114,19 +167,73 @@ func (x *masterDispatcherListenServer) Send(m *Event) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _MasterDispatcher_ReportStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MasterDispatcherServer).ReportStatus(&masterDispatcherReportStatusServer{stream})
+}
+
+type MasterDispatcher_ReportStatusServer interface {
+	SendAndClose(*EmptyRequest) error
+	Recv() (*WorkerStatusReport, error)
+	grpc.ServerStream
+}
+
+type masterDispatcherReportStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *masterDispatcherReportStatusServer) SendAndClose(m *EmptyRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *masterDispatcherReportStatusServer) Recv() (*WorkerStatusReport, error) {
+	m := new(WorkerStatusReport)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _MasterDispatcher_WorkerStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterDispatcherServer).WorkerStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/service.MasterDispatcher/WorkerStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterDispatcherServer).WorkerStatus(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MasterDispatcher_ServiceDesc is the grpc.ServiceDesc for MasterDispatcher service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var MasterDispatcher_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "service.MasterDispatcher",
 	HandlerType: (*MasterDispatcherServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "WorkerStatus",
+			Handler:    _MasterDispatcher_WorkerStatus_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Listen",
 			Handler:       _MasterDispatcher_Listen_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ReportStatus",
+			Handler:       _MasterDispatcher_ReportStatus_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "master.proto",
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/eolinker/eosc"
 	"github.com/eolinker/eosc/log"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -22,6 +23,27 @@ func RegisterSetting(name string, driver eosc.ISetting) error {
 type ISettings interface {
 	registerSetting(name string, driver eosc.ISetting) error
 	eosc.ISettings
+	Batch(name string, org []byte, variable eosc.IVariable) (*BatchResult, error)
+	SetBatch(name string, org []byte, result *BatchResult, variable eosc.IVariable)
+}
+
+// BatchItem 批量配置中的一项，每一项对应一个派生的worker
+type BatchItem struct {
+	Id          string
+	Profession  string
+	Name        string
+	Driver      string
+	Description string
+	Body        []byte
+	// Variables 该项使用的变量
+	Variables []string
+}
+
+// BatchResult 批量配置与 AllWorkers 的差异
+type BatchResult struct {
+	Create []*BatchItem
+	Update []*BatchItem
+	Delete []string
 }
 type tSettings struct {
 	lock      sync.RWMutex
@@ -30,91 +52,62 @@ type tSettings struct {
 	orgConfig map[string][]byte
 }
 
-//func (s *tSettings) Set(name string, body []byte, variable eosc.IVariable) (format interface{}, update []*eosc.WorkerConfig, delete []string, err error) {
-//	driver, has := s.GetDriver(name)
-//	if !has {
-//		err = eosc.ErrorDriverNotExist
-//		return
-//	}
-//	configType := driver.ConfigType()
-//	switch driver.Mode() {
-//	case eosc.SettingModeReadonly:
-//		err = eosc.ErrorUnsupportedKind
-//		return
-//	case eosc.SettingModeSingleton:
-//		{
-//			orgConfig := make(map[string]interface{})
-//			err = json.Unmarshal(body, &orgConfig)
-//			if err != nil {
-//				return
-//			}
-//
-//			err = s.SettingWorker(name, body, variable)
-//			if err != nil {
-//				return
-//			}
-//
-//			config := formatConfig(orgConfig, configType)
-//			s.lock.Lock()
-//			s.configs[name] = config
-//			s.orgConfig[name] = body
-//			s.lock.Unlock()
-//			format = config
-//			outputBody, _ := json.Marshal(config)
-//			update = []*eosc.WorkerConfig{
-//				{
-//					Id:          fmt.Sprintf("%s@setting", name),
-//					Profession:  "setting",
-//					Name:        name,
-//					Driver:      name,
-//					Create:      eosc.Now(),
-//					Update:      eosc.Now(),
-//					Body:        outputBody,
-//					Description: "",
-//				},
-//			}
-//			delete = nil
-//			return
-//		}
-//	case eosc.SettingModeBatch:
-//		orgs := splitConfig(body)
-//		cfgs := make([]interface{}, 0, len(orgs))
-//		orgObjs := make([]map[string]interface{}, 0, len(orgs))
-//		usagesAll := make([][]string, 0, len(orgs))
-//		for _, org := range orgs {
-//			cfg, usages, errI := variable.Unmarshal(org, configType)
-//			if errI != nil {
-//				err = errI
-//				return
-//			}
-//			orgConfig := make(map[string]interface{})
-//			err = json.Unmarshal(body, &orgConfig)
-//			if err != nil {
-//				return
-//			}
-//			orgObjs = append(orgObjs, orgConfig)
-//			cfgs = append(cfgs, cfg)
-//			usagesAll = append(usagesAll, usages)
-//		}
-//		var updateIds []string
-//		updateIds, delete, err = driver.Set(cfgs...)
-//		if err != nil {
-//			return
-//		}
-//		for i := range cfgs {
-//			variable.SetVariablesById(update[i].Id, usagesAll[i])
-//		}
-//		for _, id := range delete {
-//			variable.RemoveRequire(id)
-//		}
-//
-//		for i := range orgObjs {
-//			orgObjs[i] = formatConfig(orgObjs[i], configType)
-//		}
-//		format = orgObjs
-//	}
-//	return
-//}
+// Batch 解析批量配置，body为列表，每一项通过 driver.Check 得到对应的worker，并与 driver.AllWorkers 对比得到需要新建、更新和删除的worker
+func (s *tSettings) Batch(name string, org []byte, variable eosc.IVariable) (*BatchResult, error) {
+	driver, has := s.GetDriver(name)
+	if !has {
+		return nil, eosc.ErrorDriverNotExist
+	}
+	if driver.Mode() != eosc.SettingModeBatch {
+		return nil, eosc.ErrorUnsupportedKind
+	}
+	configType := driver.ConfigType()
+	exists := make(map[string]bool)
+	for _, id := range driver.AllWorkers() {
+		exists[id] = true
+	}
+	result := new(BatchResult)
+	items := make(map[string]bool)
+	for i, body := range splitConfig(org) {
+		cfg, used, err := variable.Unmarshal(body, configType)
+		if err != nil {
+			return nil, fmt.Errorf("item %d:%w", i, err)
+		}
+		profession, workerName, driverName, desc, err := driver.Check(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("item %d:%w", i, err)
+		}
+		id, ok := eosc.ToWorkerId(workerName, profession)
+		if !ok {
+			return nil, fmt.Errorf("item %d:invalid worker %s@%s", i, workerName, profession)
+		}
+		if items[id] {
+			return nil, fmt.Errorf("item %d:duplicate worker %s", i, id)
+		}
+		items[id] = true
+		item := &BatchItem{
+			Id:          id,
+			Profession:  profession,
+			Name:        workerName,
+			Driver:      driverName,
+			Description: desc,
+			Body:        body,
+			Variables:   used,
+		}
+		if exists[id] {
+			result.Update = append(result.Update, item)
+		} else {
+			result.Create = append(result.Create, item)
+		}
+	}
+	for id := range exists {
+		if !items[id] {
+			result.Delete = append(result.Delete, id)
+		}
+	}
+	sort.Strings(result.Delete)
+	return result, nil
+}
 
 func (s *tSettings) Update(name string, variable eosc.IVariable) error {
 	log.Debug("setting update:", name)
@@ -181,7 +174,13 @@ func (s *tSettings) GetConfig(name string) interface{} {
 			}
 			return driver.Get()
 		case eosc.SettingModeBatch:
-			return nil
+			s.lock.RLock()
+			v, yes := s.configs[name]
+			s.lock.RUnlock()
+			if yes {
+				return v
+			}
+			return driver.Get()
 		}
 	}
 	return nil
@@ -195,7 +194,11 @@ func (s *tSettings) SettingWorker(name string, org []byte, variable eosc.IVariab
 		return eosc.ErrorDriverNotExist
 	}
 
-	if driver.Mode() != eosc.SettingModeSingleton {
+	switch driver.Mode() {
+	case eosc.SettingModeSingleton:
+	case eosc.SettingModeBatch:
+		return s.settingBatch(name, org, variable)
+	default:
 		return eosc.ErrorUnsupportedKind
 	}
 	configType := driver.ConfigType()
@@ -216,6 +219,43 @@ func (s *tSettings) SettingWorker(name string, org []byte, variable eosc.IVariab
 	return nil
 
 }
+
+// settingBatch 批量配置的每一项作为独立的worker保存，这里只记录各项使用的变量以及用于 GetConfig 的配置
+func (s *tSettings) settingBatch(name string, org []byte, variable eosc.IVariable) error {
+	result, err := s.Batch(name, org, variable)
+	if err != nil {
+		return err
+	}
+	s.SetBatch(name, org, result, variable)
+	return nil
+}
+
+// SetBatch 使用已经计算好的 Batch 结果记录各项使用的变量以及配置，不再重新计算差异
+func (s *tSettings) SetBatch(name string, org []byte, result *BatchResult, variable eosc.IVariable) {
+	driver, has := s.GetDriver(name)
+	if !has {
+		return
+	}
+	configType := driver.ConfigType()
+	for _, items := range [][]*BatchItem{result.Create, result.Update} {
+		for _, item := range items {
+			variable.SetVariablesById(item.Id, item.Variables)
+		}
+	}
+	for _, id := range result.Delete {
+		variable.RemoveRequire(id)
+	}
+	bodies := splitConfig(org)
+	configs := make([]interface{}, 0, len(bodies))
+	for _, body := range bodies {
+		configs = append(configs, FormatConfig(body, configType))
+	}
+	s.lock.Lock()
+	s.configs[name] = configs
+	s.orgConfig[name] = org
+	s.lock.Unlock()
+}
+
 func (s *tSettings) registerSetting(name string, driver eosc.ISetting) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
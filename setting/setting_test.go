@@ -0,0 +1,80 @@
+package setting
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/variable"
+)
+
+type testPlugin struct {
+	Name   string `json:"name"`
+	Config string `json:"config"`
+}
+
+type testBatchSetting struct {
+	workers []string
+}
+
+func (t *testBatchSetting) ConfigType() reflect.Type {
+	return reflect.TypeOf(new(testPlugin))
+}
+
+func (t *testBatchSetting) Set(conf interface{}) (err error) {
+	return nil
+}
+
+func (t *testBatchSetting) Get() interface{} {
+	return nil
+}
+
+func (t *testBatchSetting) Mode() eosc.SettingMode {
+	return eosc.SettingModeBatch
+}
+
+func (t *testBatchSetting) Check(cfg interface{}) (profession, name, driver, desc string, err error) {
+	p := cfg.(*testPlugin)
+	return "plugin", p.Name, "plugin", "", nil
+}
+
+func (t *testBatchSetting) AllWorkers() []string {
+	return t.workers
+}
+
+func TestSettings_Batch(t *testing.T) {
+	s := newSettings()
+	s.registerSetting("plugins", &testBatchSetting{workers: []string{"auth@plugin", "limit@plugin"}})
+	data, _ := json.Marshal(map[string]string{"key": "v1"})
+	vs := variable.NewVariables(map[string][]byte{"default": data})
+
+	body := []byte(`[{"name":"auth","config":"${key@default}"},{"name":"cors","config":"any"}]`)
+	result, err := s.Batch("plugins", body, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Create) != 1 || result.Create[0].Id != "cors@plugin" {
+		t.Errorf("create = %v", result.Create)
+	}
+	if len(result.Update) != 1 || result.Update[0].Id != "auth@plugin" || !reflect.DeepEqual(result.Update[0].Variables, []string{"key@default"}) {
+		t.Errorf("update = %v", result.Update)
+	}
+	if !reflect.DeepEqual(result.Delete, []string{"limit@plugin"}) {
+		t.Errorf("delete = %v", result.Delete)
+	}
+
+	if err := s.SettingWorker("plugins", body, vs); err != nil {
+		t.Fatal(err)
+	}
+	if got := vs.GetIdsByVariable("key@default"); !reflect.DeepEqual(got, []string{"auth@plugin"}) {
+		t.Errorf("variable used by %v", got)
+	}
+	if configs, ok := s.GetConfig("plugins").([]interface{}); !ok || len(configs) != 2 {
+		t.Errorf("config = %v", s.GetConfig("plugins"))
+	}
+
+	if _, err := s.Batch("plugins", []byte(`[{"name":"auth"},{"name":"auth"}]`), vs); err == nil {
+		t.Error("duplicate item should fail")
+	}
+}
@@ -37,6 +37,35 @@ func ToWorkerId(name, profession string) (string, bool) {
 	return name, true
 }
 
+const (
+	// TenantSeparator 租户与worker名称的分隔符，租户下worker的id为 {tenant}/{name}@{profession}
+	TenantSeparator = "/"
+	// TenantDefault 默认租户，默认租户下的worker id不带租户前缀
+	TenantDefault = "default"
+)
+
+// ToTenantName 返回租户下的worker名称，默认租户不加前缀
+func ToTenantName(tenant, name string) string {
+	tenant = strings.ToLower(tenant)
+	if tenant == "" || tenant == TenantDefault {
+		return name
+	}
+	return tenant + TenantSeparator + name
+}
+
+// SplitTenant 从worker名称或者id中拆分出租户，默认租户返回空
+func SplitTenant(name string) (tenant string, rest string) {
+	index := strings.Index(name, TenantSeparator)
+	if index < 0 {
+		return "", name
+	}
+	tenant = strings.ToLower(name[:index])
+	if tenant == TenantDefault {
+		tenant = ""
+	}
+	return tenant, name[index+1:]
+}
+
 func SplitWorkerId(id string) (profession string, name string, success bool) {
 	id = strings.ToLower(id)
 	index := strings.Index(id, "@")
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// RewriteRequire 按照配置类型查找body中的RequireId字段，将引用from的值替换为to
+// body 为json解析后的通用结构，返回替换后的值以及是否有修改
+func RewriteRequire(t reflect.Type, body interface{}, from, to string) (interface{}, bool) {
+	return MapRequire(t, body, func(id string) (string, bool) {
+		return to, strings.EqualFold(id, from)
+	})
+}
+
+// MapRequire 按照配置类型查找body中的RequireId字段，使用fn转换引用的值，fn返回false时保持原值
+func MapRequire(t reflect.Type, body interface{}, fn func(id string) (string, bool)) (interface{}, bool) {
+	if t == nil || body == nil {
+		return body, false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if TypeName(t) == _RequireTypeName {
+		if s, ok := body.(string); ok {
+			if v, c := fn(s); c {
+				return v, true
+			}
+		}
+		return body, false
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := body.(map[string]interface{})
+		if !ok {
+			return body, false
+		}
+		return m, mapStruct(t, m, fn)
+	case reflect.Slice, reflect.Array:
+		list, ok := body.([]interface{})
+		if !ok {
+			return body, false
+		}
+		changed := false
+		for i, v := range list {
+			if nv, c := MapRequire(t.Elem(), v, fn); c {
+				list[i] = nv
+				changed = true
+			}
+		}
+		return list, changed
+	case reflect.Map:
+		m, ok := body.(map[string]interface{})
+		if !ok {
+			return body, false
+		}
+		changed := false
+		for k, v := range m {
+			if nv, c := MapRequire(t.Elem(), v, fn); c {
+				m[k] = nv
+				changed = true
+			}
+		}
+		return m, changed
+	}
+	return body, false
+}
+
+func mapStruct(t reflect.Type, m map[string]interface{}, fn func(id string) (string, bool)) bool {
+	changed := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if f.Anonymous {
+				// 匿名字段的属性与外层在同一级
+				ft := f.Type
+				for ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct && mapStruct(ft, m, fn) {
+					changed = true
+				}
+				continue
+			}
+			name = f.Name
+		}
+		v, has := m[name]
+		if !has {
+			continue
+		}
+		if nv, c := MapRequire(f.Type, v, fn); c {
+			m[name] = nv
+			changed = true
+		}
+	}
+	return changed
+}
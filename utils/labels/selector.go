@@ -0,0 +1,278 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	OpEquals    = "="
+	OpNotEquals = "!="
+	OpIn        = "in"
+	OpNotIn     = "notin"
+	OpExists    = "exists"
+	OpNotExists = "!"
+)
+
+var (
+	keyRegexp   = regexp.MustCompile(`^([a-zA-Z0-9]([-a-zA-Z0-9_./]*[a-zA-Z0-9])?)$`)
+	valueRegexp = regexp.MustCompile(`^(([a-zA-Z0-9]([-a-zA-Z0-9_.]*[a-zA-Z0-9])?)?)$`)
+)
+
+const (
+	maxKeyLength   = 253
+	maxValueLength = 63
+)
+
+// Requirement 单个条件，多个值时只用于 in、notin
+type Requirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+func (r *Requirement) Matches(labels map[string]string) bool {
+	v, has := labels[r.Key]
+	switch r.Operator {
+	case OpEquals:
+		return has && v == r.Values[0]
+	case OpNotEquals:
+		return !has || v != r.Values[0]
+	case OpIn:
+		return has && contains(r.Values, v)
+	case OpNotIn:
+		return !has || !contains(r.Values, v)
+	case OpExists:
+		return has
+	case OpNotExists:
+		return !has
+	}
+	return false
+}
+
+func (r *Requirement) String() string {
+	switch r.Operator {
+	case OpExists:
+		return r.Key
+	case OpNotExists:
+		return "!" + r.Key
+	case OpIn, OpNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return r.Key + r.Operator + r.Values[0]
+}
+
+// Selector 标签选择器，所有条件同时满足时匹配，空选择器匹配所有
+type Selector []*Requirement
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s Selector) String() string {
+	rs := make([]string, 0, len(s))
+	for _, r := range s {
+		rs = append(rs, r.String())
+	}
+	return strings.Join(rs, ",")
+}
+
+// Parse 解析与kubernetes一致的标签选择器，如 env=prod,team in (a,b),!deprecated
+// 支持 =、==、!=、in、notin、key(存在)、!key(不存在)
+func Parse(selector string) (Selector, error) {
+	p := &parser{input: selector}
+	return p.parse()
+}
+
+// Validate 校验标签的key及value
+func Validate(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := validateKey(k); err != nil {
+			return err
+		}
+		if err := validateValue(labels[k]); err != nil {
+			return fmt.Errorf("label %s:%w", k, err)
+		}
+	}
+	return nil
+}
+
+func validateKey(k string) error {
+	if len(k) == 0 || len(k) > maxKeyLength || !keyRegexp.MatchString(k) {
+		return fmt.Errorf("invalid label key:%q", k)
+	}
+	return nil
+}
+
+func validateValue(v string) error {
+	if len(v) > maxValueLength || !valueRegexp.MatchString(v) {
+		return fmt.Errorf("invalid label value:%q", v)
+	}
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, i := range list {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parse() (Selector, error) {
+	s := make(Selector, 0)
+	p.skipSpace()
+	if p.end() {
+		return s, nil
+	}
+	for {
+		r, err := p.requirement()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, r)
+		p.skipSpace()
+		if p.end() {
+			return s, nil
+		}
+		if p.input[p.pos] != ',' {
+			return nil, p.errorf("expected ','")
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) requirement() (*Requirement, error) {
+	p.skipSpace()
+	if p.peek("!") && !p.peek("!=") {
+		p.pos++
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		return &Requirement{Key: key, Operator: OpNotExists}, nil
+	}
+	key, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	switch {
+	case p.end() || p.input[p.pos] == ',':
+		return &Requirement{Key: key, Operator: OpExists}, nil
+	case p.peek("=="):
+		p.pos += 2
+		return p.single(key, OpEquals)
+	case p.peek("!="):
+		p.pos += 2
+		return p.single(key, OpNotEquals)
+	case p.peek("="):
+		p.pos++
+		return p.single(key, OpEquals)
+	}
+	word := p.word()
+	switch word {
+	case OpIn, OpNotIn:
+		values, err := p.values()
+		if err != nil {
+			return nil, err
+		}
+		return &Requirement{Key: key, Operator: word, Values: values}, nil
+	}
+	return nil, p.errorf("unknown operator %q", word)
+}
+
+func (p *parser) single(key, op string) (*Requirement, error) {
+	p.skipSpace()
+	value := p.word()
+	if err := validateValue(value); err != nil {
+		return nil, err
+	}
+	return &Requirement{Key: key, Operator: op, Values: []string{value}}, nil
+}
+
+func (p *parser) values() ([]string, error) {
+	p.skipSpace()
+	if !p.peek("(") {
+		return nil, p.errorf("expected '('")
+	}
+	p.pos++
+	values := make([]string, 0)
+	for {
+		p.skipSpace()
+		value := p.word()
+		if err := validateValue(value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		p.skipSpace()
+		switch {
+		case p.peek(","):
+			p.pos++
+		case p.peek(")"):
+			p.pos++
+			sort.Strings(values)
+			return values, nil
+		default:
+			return nil, p.errorf("expected ',' or ')'")
+		}
+	}
+}
+
+func (p *parser) key() (string, error) {
+	p.skipSpace()
+	key := p.word()
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// word 读取到空白或者分隔符为止
+func (p *parser) word() string {
+	start := p.pos
+	for !p.end() && !strings.ContainsRune(" \t,()=!", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *parser) peek(s string) bool {
+	return strings.HasPrefix(p.input[p.pos:], s)
+}
+
+func (p *parser) end() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) skipSpace() {
+	for !p.end() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid selector %q at %d: %s", p.input, p.pos, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,53 @@
+package labels
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	labels := map[string]string{"env": "prod", "team": "a", "app.io/tier": "web"}
+	tests := []struct {
+		selector string
+		want     bool
+		err      bool
+	}{
+		{selector: "", want: true},
+		{selector: "env=prod", want: true},
+		{selector: "env==prod,team=b", want: false},
+		{selector: "env!=test", want: true},
+		{selector: "team in (a, b)", want: true},
+		{selector: "team notin (a,b)", want: false},
+		{selector: "app.io/tier,!deprecated", want: true},
+		{selector: "!env", want: false},
+		{selector: "missing!=x", want: true},
+		{selector: "env = prod , team in (b,c)", want: false},
+		{selector: "env in a", err: true},
+		{selector: "env like prod", err: true},
+		{selector: "env=prod,", err: true},
+		{selector: "=prod", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			s, err := Parse(tt.selector)
+			if (err != nil) != tt.err {
+				t.Fatalf("Parse() error = %v, want error %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got := s.Matches(labels); got != tt.want {
+				t.Errorf("Matches() = %v, want %v, selector %s", got, tt.want, s)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(map[string]string{"env": "prod", "app.io/name": ""}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := Validate(map[string]string{"env": "a b"}); err == nil {
+		t.Error("Validate() want error for value with space")
+	}
+	if err := Validate(map[string]string{"-env": "prod"}); err == nil {
+		t.Error("Validate() want error for invalid key")
+	}
+}
@@ -159,6 +159,9 @@ type Schema struct {
 	Skill                string              `json:"skill,omitempty"`
 	Switch               string              `json:"switch,omitempty"`
 	Label                string              `json:"label,omitempty"`
+
+	// skipped 带有skip标签的字段，不导出到schema但依旧可以出现在配置中
+	skipped map[string]bool
 }
 
 func (s *Schema) findProperties(name string) *Schema {
@@ -577,6 +580,12 @@ func generateWithMode(t reflect.Type, mode Mode, schema *Schema) (r *Schema, err
 
 			if s == nil {
 				// Skip deliberately filtered out items
+				if name != "-" {
+					if schema.skipped == nil {
+						schema.skipped = make(map[string]bool)
+					}
+					schema.skipped[name] = true
+				}
 				continue
 			}
 
@@ -0,0 +1,316 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError 校验失败的字段，path 为字段路径，如 nodes[0].host
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationError 配置校验失败时返回的所有字段错误
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	ms := make([]string, 0, len(e))
+	for _, f := range e {
+		ms = append(ms, f.Error())
+	}
+	return fmt.Sprintf("invalid config: %s", strings.Join(ms, "; "))
+}
+
+// Validate 使用schema校验json解析后的数据，ignore 为根对象中允许出现的额外字段
+// 包含变量(${...})的字符串不做类型及取值的校验，由变量替换后的解析过程负责
+func (s *Schema) Validate(value interface{}, ignore ...string) error {
+	return s.validate(value, nil, ignore)
+}
+
+// ValidatePatch 与 Validate 相同，但只在 fields 指定的根对象字段中检查未知属性
+// 用于PATCH，已保存配置中的未知字段不影响修改其他字段
+func (s *Schema) ValidatePatch(value interface{}, fields []string, ignore ...string) error {
+	strict := make(map[string]bool, len(fields))
+	for _, k := range fields {
+		strict[k] = true
+	}
+	return s.validate(value, strict, ignore)
+}
+
+func (s *Schema) validate(value interface{}, strict map[string]bool, ignore []string) error {
+	v := &validator{ignore: make(map[string]bool, len(ignore)), strict: strict}
+	for _, k := range ignore {
+		v.ignore[k] = true
+	}
+	v.validate(s, "", value, true)
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+type validator struct {
+	ignore map[string]bool
+	// strict 不为nil时只在这些根对象字段中检查未知属性，field 为当前校验的根对象字段
+	strict map[string]bool
+	field  string
+	errors ValidationError
+}
+
+func (v *validator) fail(path string, format string, args ...interface{}) {
+	v.errors = append(v.errors, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func isVariable(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(s, "${")
+}
+
+func (v *validator) validate(s *Schema, path string, value interface{}, root bool) {
+	if s == nil || value == nil || isVariable(value) {
+		return
+	}
+	switch s.Type {
+	case TypeObject:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, "expected object but got %s", typeOf(value))
+			return
+		}
+		v.validateObject(s, path, m, root)
+		return
+	case TypeArray:
+		list, ok := value.([]interface{})
+		if !ok {
+			v.fail(path, "expected array but got %s", typeOf(value))
+			return
+		}
+		if s.MinItems != nil && uint64(len(list)) < *s.MinItems {
+			v.fail(path, "expected at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && uint64(len(list)) > *s.MaxItems {
+			v.fail(path, "expected at most %d items", *s.MaxItems)
+		}
+		for i, item := range list {
+			v.validate(s.Items, fmt.Sprintf("%s[%d]", path, i), item, false)
+		}
+		return
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			v.fail(path, "expected string but got %s", typeOf(value))
+			return
+		}
+		v.validateString(s, path, str)
+	case TypeInteger, TypeNumber:
+		n, ok := toNumber(value)
+		if !ok {
+			v.fail(path, "expected %s but got %s", s.Type, typeOf(value))
+			return
+		}
+		if s.Type == TypeInteger && n != math.Trunc(n) {
+			v.fail(path, "expected integer but got %v", n)
+			return
+		}
+		v.validateNumber(s, path, n)
+	case TypeBoolean:
+		switch b := value.(type) {
+		case bool:
+		case string:
+			// 与变量解析保持一致，允许使用字符串表示布尔值
+			if _, err := strconv.ParseBool(b); err != nil {
+				v.fail(path, "expected boolean but got %q", b)
+				return
+			}
+		default:
+			v.fail(path, "expected boolean but got %s", typeOf(value))
+			return
+		}
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		v.fail(path, "value %v is not one of %v", value, s.Enum)
+	}
+}
+
+func (v *validator) validateObject(s *Schema, path string, m map[string]interface{}, root bool) {
+	for _, name := range s.Required {
+		if _, has := lookup(m, name); !has {
+			v.fail(joinPath(path, name), "is required")
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if root {
+			v.field = k
+		}
+		child := joinPath(path, k)
+		if ps, has := findProperty(s, k); has {
+			v.validate(ps, child, m[k], false)
+			continue
+		}
+		if s.AdditionalProperties != nil {
+			v.validate(s.AdditionalProperties, child, m[k], false)
+			continue
+		}
+		if s.skipped[k] || s.skipped[strings.ToLower(k)] || (root && v.ignore[k]) {
+			continue
+		}
+		if len(s.Properties) == 0 && s.EOType != TypeObject {
+			// interface 等无法确定结构的对象不校验属性
+			continue
+		}
+		if v.strict != nil && !v.strict[v.field] {
+			continue
+		}
+		v.fail(child, "unknown property")
+	}
+}
+
+func (v *validator) validateString(s *Schema, path string, str string) {
+	if s.MinLength != nil && uint64(len(str)) < *s.MinLength {
+		v.fail(path, "expected length >= %d", *s.MinLength)
+	}
+	if s.MaxLength != nil && uint64(len(str)) > *s.MaxLength {
+		v.fail(path, "expected length <= %d", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		if reg, err := regexp.Compile(s.Pattern); err == nil && !reg.MatchString(str) {
+			v.fail(path, "value %q does not match pattern %s", str, s.Pattern)
+		}
+	}
+	if str == "" {
+		return
+	}
+	switch s.Format {
+	case "uri", "url":
+		if u, err := url.Parse(str); err != nil || u.Scheme == "" {
+			v.fail(path, "value %q is not a valid uri", str)
+		}
+	case "ip":
+		if net.ParseIP(str) == nil {
+			v.fail(path, "value %q is not a valid ip", str)
+		}
+	case "ipv4":
+		if ip := net.ParseIP(str); ip == nil || ip.To4() == nil {
+			v.fail(path, "value %q is not a valid ipv4", str)
+		}
+	case "ipv6":
+		if ip := net.ParseIP(str); ip == nil || ip.To4() != nil {
+			v.fail(path, "value %q is not a valid ipv6", str)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			v.fail(path, "value %q is not a valid date-time", str)
+		}
+	}
+}
+
+func (v *validator) validateNumber(s *Schema, path string, n float64) {
+	if s.Minimum != nil {
+		if s.ExclusiveMinimum != nil && *s.ExclusiveMinimum {
+			if n <= *s.Minimum {
+				v.fail(path, "expected value > %v", *s.Minimum)
+			}
+		} else if n < *s.Minimum {
+			v.fail(path, "expected value >= %v", *s.Minimum)
+		}
+	}
+	if s.Maximum != nil {
+		if s.ExclusiveMaximum != nil && *s.ExclusiveMaximum {
+			if n >= *s.Maximum {
+				v.fail(path, "expected value < %v", *s.Maximum)
+			}
+		} else if n > *s.Maximum {
+			v.fail(path, "expected value <= %v", *s.Maximum)
+		}
+	}
+}
+
+// toNumber 与变量解析保持一致，允许使用字符串表示数字
+func toNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	target := fmt.Sprint(value)
+	for _, e := range enum {
+		if fmt.Sprint(e) == target {
+			return true
+		}
+	}
+	return false
+}
+
+// findProperty 属性名优先精确匹配，与json解析一致不区分大小写
+func findProperty(s *Schema, name string) (*Schema, bool) {
+	if ps, has := s.Properties[name]; has {
+		return ps, true
+	}
+	for k, ps := range s.Properties {
+		if strings.EqualFold(k, name) {
+			return ps, true
+		}
+	}
+	return nil, false
+}
+
+func lookup(m map[string]interface{}, name string) (interface{}, bool) {
+	if v, has := m[name]; has {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return TypeObject
+	case []interface{}:
+		return TypeArray
+	case string:
+		return TypeString
+	case float64:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	}
+	return fmt.Sprintf("%T", value)
+}
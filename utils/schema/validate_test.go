@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type validateNode struct {
+	Host   string `json:"host" format:"ip"`
+	Weight int    `json:"weight" minimum:"1" maximum:"100"`
+}
+
+type validateConfig struct {
+	Scheme  string         `json:"scheme" enum:"http,https"`
+	Name    string         `json:"name" required:"true" pattern:"^[a-z]+$"`
+	Url     string         `json:"url" format:"uri"`
+	Nodes   []validateNode `json:"nodes"`
+	Target  RequireId      `json:"target" skill:"test" required:"false"`
+	Headers map[string]int `json:"headers"`
+	Extra   string         `json:"extra" skip:""`
+}
+
+func TestSchema_Validate(t *testing.T) {
+	sc, err := Generate(reflect.TypeOf(validateConfig{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		body  string
+		paths []string
+	}{
+		{
+			name: "valid",
+			body: `{"id":"demo@service","scheme":"https","name":"demo","url":"http://a.com","nodes":[{"host":"127.0.0.1","weight":10}],"headers":{"a":1},"extra":"x"}`,
+		},
+		{
+			name: "variable",
+			body: `{"name":"demo","scheme":"${scheme}","nodes":[{"host":"${host}","weight":"${weight}"}]}`,
+		},
+		{
+			name:  "required",
+			body:  `{"scheme":"http"}`,
+			paths: []string{"name"},
+		},
+		{
+			name:  "enum and pattern",
+			body:  `{"name":"Demo","scheme":"ftp"}`,
+			paths: []string{"name", "scheme"},
+		},
+		{
+			name:  "nested",
+			body:  `{"name":"demo","url":"a.com","nodes":[{"host":"a.b","weight":0}],"headers":{"a":"x"}}`,
+			paths: []string{"headers.a", "nodes[0].host", "nodes[0].weight", "url"},
+		},
+		{
+			name:  "unknown property",
+			body:  `{"name":"demo","other":1,"nodes":[{"host":"127.0.0.1","port":80}]}`,
+			paths: []string{"nodes[0].port", "other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body interface{}
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatal(err)
+			}
+			err := sc.Validate(body, "id")
+			if len(tt.paths) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			var ve ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("Validate() error = %v, want ValidationError", err)
+			}
+			paths := make([]string, 0, len(ve))
+			for _, f := range ve {
+				paths = append(paths, f.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("Validate() paths = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestSchema_ValidatePatch(t *testing.T) {
+	sc, err := Generate(reflect.TypeOf(validateConfig{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body interface{}
+	json.Unmarshal([]byte(`{"name":"demo","scheme":"ftp","old":1,"nodes":[{"host":"127.0.0.1","weight":10,"port":80}]}`), &body)
+	// 未修改字段中的未知属性不报错，其他校验依旧生效
+	err = sc.ValidatePatch(body, []string{"scheme"})
+	var ve ValidationError
+	if !errors.As(err, &ve) || len(ve) != 1 || ve[0].Path != "scheme" {
+		t.Fatalf("ValidatePatch() error = %v", err)
+	}
+	err = sc.ValidatePatch(body, []string{"nodes", "old"})
+	if !errors.As(err, &ve) || len(ve) != 3 {
+		t.Fatalf("ValidatePatch() error = %v", err)
+	}
+}
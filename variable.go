@@ -12,8 +12,11 @@ var (
 type IVariable interface {
 	SetByNamespace(namespace string, variables map[string]string) error
 	GetByNamespace(namespace string) (map[string]string, bool)
+	All() map[string]map[string]string
 	SetVariablesById(id string, variables []string)
 	RemoveRequire(id string)
+	GetVariablesById(id string) []string
+	GetIdsByVariable(variable string) []string
 	Unmarshal(buf []byte, typ reflect.Type) (interface{}, []string, error)
 	Check(namespace string, variables map[string]string) ([]string, IVariable, error)
 	Get(id string) (string, bool)
@@ -1,14 +1,21 @@
 package variable
 
 import (
-	"github.com/eolinker/eosc"
+	"errors"
+	"fmt"
 	"strings"
+
+	"github.com/eolinker/eosc"
 )
 
 const (
 	dollarSign = 36  // $
 	leftSign   = 123 // {
 	rightSign  = 125 // }
+
+	pipeSign      = "|"
+	defaultSign   = ":-"
+	listSeparator = ","
 )
 
 const (
@@ -24,37 +31,85 @@ const (
 
 	//EndInputStatus 结束输入状态
 	EndInputStatus
+
+	//EscapeStatus 转义状态，$${ 输出为 ${
+	EscapeStatus
 )
 
 func NewBuilder(str string) *Builder {
 	return &Builder{str: str}
 }
 
+// Builder 替换字符串中的变量，表达式为 ${name@namespace:-default|filter:arg}
+// 变量不存在时使用 :- 后的默认值，| 后为依次执行的过滤器，$${ 表示字符 ${
 type Builder struct {
 	str string
 	//separator     string
 	//defaultSuffix string
 }
 
+// Replace 返回替换后的字符串，过滤器返回多个值时使用 , 连接
 func (b *Builder) Replace(variables eosc.IVariable) (string, []string, bool) {
-	strBuilder := strings.Builder{}
+	rs, useVariable, err := b.replace(variables)
+	if err != nil {
+		return "", nil, false
+	}
+	return rs.String(), useVariable, true
+}
+
+// ReplaceList 字符串只包含一个变量表达式时返回表达式的所有值(如 split 过滤器的结果)，否则返回替换后的字符串
+// 引用了不读取值的外部变量时返回 ErrorVariableSkipped 以及使用的变量
+func (b *Builder) ReplaceList(variables eosc.IVariable) ([]string, []string, error) {
+	rs, useVariable, err := b.replace(variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rs.skipped {
+		return nil, useVariable, ErrorVariableSkipped
+	}
+	if rs.single {
+		return rs.values, useVariable, nil
+	}
+	return []string{rs.String()}, useVariable, nil
+}
+
+type replaceResult struct {
+	strings.Builder
+	// single 字符串只包含一个变量表达式
+	single bool
+	values []string
+	// skipped 引用了不读取值的外部变量
+	skipped bool
+}
+
+func (b *Builder) replace(variables eosc.IVariable) (*replaceResult, []string, error) {
+	rs := new(replaceResult)
 	varBuilder := strings.Builder{}
 	status := CurrentStatus
 	startIndex := 0
+	expressions, literal := 0, false
 	useVariable := make([]string, 0, variables.Len())
 	for i, s := range b.str {
 		oldStatus := status
 		status = toggleStatus(status, s)
 		switch status {
 		case CurrentStatus:
-			if oldStatus == ReadyStatus {
-				strBuilder.WriteString(b.str[startIndex : i+1])
+			literal = true
+			if oldStatus == EscapeStatus && s == leftSign {
+				// $${ 转义为 ${
+				rs.WriteString("${")
+				startIndex = i + 1
+				continue
+			}
+			if oldStatus == ReadyStatus || oldStatus == EscapeStatus {
+				rs.WriteString(b.str[startIndex : i+1])
 				startIndex = i + 1
 				continue
 			}
-			strBuilder.WriteRune(s)
+			rs.WriteRune(s)
 		case ReadyStatus:
 			startIndex = i
+		case EscapeStatus:
 		case InputStatus:
 			if oldStatus == ReadyStatus {
 				// 刚切换状态，忽略此时的字符
@@ -62,27 +117,71 @@ func (b *Builder) Replace(variables eosc.IVariable) (string, []string, bool) {
 			}
 			varBuilder.WriteRune(s)
 		case EndInputStatus:
-			id := varBuilder.String()
-
-			v, ok := variables.Get(id)
-			if !ok {
-				// 变量不存在，报错
-				return "", nil, false
+			id, values, err := evaluate(varBuilder.String(), variables)
+			if id != "" {
+				// 使用默认值时同样记录变量，变量新增后需要重新生成配置
+				useVariable = append(useVariable, id)
 			}
-
-			useVariable = append(useVariable, id)
-			strBuilder.WriteString(v)
+			if errors.Is(err, ErrorVariableSkipped) {
+				rs.skipped = true
+				err = nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			rs.values = values
+			expressions++
+			rs.WriteString(strings.Join(values, listSeparator))
 			varBuilder.Reset()
 			startIndex = i + 1
 			status = CurrentStatus
 		}
 
 	}
-	if status == InputStatus {
-		strBuilder.WriteString(b.str[startIndex:])
+	switch status {
+	case InputStatus, ReadyStatus, EscapeStatus:
+		literal = true
+		rs.WriteString(b.str[startIndex:])
 	}
+	rs.single = expressions == 1 && !literal
+	return rs, useVariable, nil
+}
 
-	return strBuilder.String(), useVariable, true
+// evaluate 计算变量表达式，返回使用的变量id和结果
+func evaluate(expression string, variables eosc.IVariable) (string, []string, error) {
+	parts := strings.Split(expression, pipeSign)
+	id, defaultValue, hasDefault := parts[0], "", false
+	if i := strings.Index(id, defaultSign); i >= 0 {
+		id, defaultValue, hasDefault = id[:i], id[i+len(defaultSign):], true
+	}
+	id = strings.TrimSpace(id)
+	if v, ok := variables.(*Variables); ok && v.isSkipped(id) {
+		return id, nil, ErrorVariableSkipped
+	}
+	value, has := variables.Get(id)
+	if !has {
+		if !hasDefault {
+			return id, nil, fmt.Errorf("%w:%s", ErrorVariableNotFound, id)
+		}
+		value = defaultValue
+	}
+	values := []string{value}
+	for _, f := range parts[1:] {
+		name, arg := strings.TrimSpace(f), ""
+		if i := strings.Index(name, ":"); i >= 0 {
+			name, arg = name[:i], name[i+1:]
+		}
+		filter, has := filters[name]
+		if !has {
+			return id, nil, fmt.Errorf("%w:%s", ErrorVariableFilter, name)
+		}
+		var err error
+		values, err = filter(values, arg)
+		if err != nil {
+			return id, nil, fmt.Errorf("%w:%s %s", ErrorVariableFilter, name, err)
+		}
+	}
+	return id, values, nil
 }
 
 func toggleStatus(status int, c rune) int {
@@ -96,6 +195,11 @@ func toggleStatus(status int, c rune) int {
 		if c == leftSign {
 			return InputStatus
 		}
+		if c == dollarSign {
+			return EscapeStatus
+		}
+		return CurrentStatus
+	case EscapeStatus:
 		return CurrentStatus
 	case InputStatus:
 		if c == rightSign {
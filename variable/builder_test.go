@@ -0,0 +1,92 @@
+package variable
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_Replace(t *testing.T) {
+	data, _ := json.Marshal(map[string]string{"host": "Example.COM", "token": "abc", "list": "a,b,c"})
+	variables := NewVariables(map[string][]byte{"default": data})
+	tests := []struct {
+		str   string
+		want  string
+		used  []string
+		fails bool
+	}{
+		{str: "http://${host@default}/", want: "http://Example.COM/", used: []string{"host@default"}},
+		{str: "${host@default|lower}", want: "example.com", used: []string{"host@default"}},
+		{str: "${token@default|base64}", want: "YWJj", used: []string{"token@default"}},
+		{str: "${port@default:-8080}", want: "8080", used: []string{"port@default"}},
+		{str: "${port@default:-LOCAL|lower}", want: "local", used: []string{"port@default"}},
+		{str: "${list@default|split:,|upper}", want: "A,B,C", used: []string{"list@default"}},
+		{str: "$${host@default} ${token@default}", want: "${host@default} abc", used: []string{"token@default"}},
+		{str: "$$ and $x and $", want: "$$ and $x and $", used: []string{}},
+		{str: "${port@default}", fails: true},
+		{str: "${host@default|unknown}", fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			got, used, ok := NewBuilder(tt.str).Replace(variables)
+			if ok == tt.fails {
+				t.Fatalf("Replace() ok = %v", ok)
+			}
+			if tt.fails {
+				return
+			}
+			if got != tt.want || !reflect.DeepEqual(used, tt.used) {
+				t.Errorf("Replace() = %s %v, want %s %v", got, used, tt.want, tt.used)
+			}
+		})
+	}
+
+	type conf struct {
+		Hosts []string `json:"hosts"`
+	}
+	v, _, err := NewParse(variables).Unmarshal([]byte(`{"hosts":"${list@default|split:,}"}`), reflect.TypeOf(conf{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := v.(conf).Hosts; !reflect.DeepEqual(hosts, []string{"a", "b", "c"}) {
+		t.Errorf("split to slice = %v", hosts)
+	}
+}
+
+func TestBuilder_ReplaceProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "password"), []byte("p@ss\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	RegisterProvider(ProviderFile, NewDirProvider(dir))
+	t.Setenv("VARIABLE_TEST_HOST", "node-1")
+	variables := NewVariables(nil)
+
+	got, used, ok := NewBuilder("${VARIABLE_TEST_HOST@env}:${password@file}").Replace(variables)
+	if !ok || got != "node-1:p@ss" {
+		t.Errorf("Replace() = %s, %v", got, ok)
+	}
+	if !reflect.DeepEqual(used, []string{"VARIABLE_TEST_HOST@env", "password@file"}) {
+		t.Errorf("used = %v", used)
+	}
+	if _, has := variables.Get("../password@file"); has {
+		t.Error("file provider should not read outside of dir")
+	}
+
+	type conf struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	v, used, err := NewParse(NewCheckVariables(nil)).Unmarshal([]byte(`{"host":"${VARIABLE_TEST_HOST@env}","port":"${VARIABLE_TEST_PORT@env}"}`), reflect.TypeOf(conf{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := v.(conf); c.Host != "" || c.Port != 0 {
+		t.Errorf("check variables should not read provider, got %v", c)
+	}
+	if len(used) != 2 {
+		t.Errorf("used = %v", used)
+	}
+}
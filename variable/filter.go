@@ -0,0 +1,44 @@
+package variable
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrorVariableFilter = errors.New("invalid variable filter")
+
+// filterFunc 变量过滤器，arg 为过滤器名称 : 之后的参数
+type filterFunc func(values []string, arg string) ([]string, error)
+
+var filters = map[string]filterFunc{
+	"base64": eachFilter(func(v string) string {
+		return base64.StdEncoding.EncodeToString([]byte(v))
+	}),
+	"lower": eachFilter(strings.ToLower),
+	"upper": eachFilter(strings.ToUpper),
+	"trim":  eachFilter(strings.TrimSpace),
+	"split": splitFilter,
+}
+
+func eachFilter(fn func(v string) string) filterFunc {
+	return func(values []string, arg string) ([]string, error) {
+		rs := make([]string, 0, len(values))
+		for _, v := range values {
+			rs = append(rs, fn(v))
+		}
+		return rs, nil
+	}
+}
+
+// splitFilter 使用 arg 拆分变量值，如 ${hosts|split:,}，结果用于数组类型的配置
+func splitFilter(values []string, arg string) ([]string, error) {
+	if arg == "" {
+		return nil, errors.New("split separator is empty")
+	}
+	rs := make([]string, 0, len(values))
+	for _, v := range values {
+		rs = append(rs, strings.Split(v, arg)...)
+	}
+	return rs, nil
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/eolinker/eosc"
+	"github.com/eolinker/eosc/log"
 	"github.com/eolinker/eosc/require"
 )
 
@@ -20,6 +21,8 @@ type Variables struct {
 	lock           sync.RWMutex
 	data           map[string]map[string]string
 	requireManager eosc.IRequires
+	// skipProvider 不读取外部变量来源的值，只记录引用
+	skipProvider bool
 }
 
 func (m *Variables) RemoveRequire(id string) {
@@ -30,9 +33,22 @@ func (m *Variables) Get(id string) (string, bool) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 	namespace, key := readId(id)
+	if p, has := GetProvider(namespace); has {
+		// 外部变量来源的namespace由节点本地读取
+		return p.Get(key)
+	}
 	vs, has := m.data[namespace]
 	if has {
 		val, has := vs[key]
+		if has && IsSecret(val) {
+			// secret变量只在替换到配置时解密
+			plain, err := DecryptSecret(val)
+			if err != nil {
+				log.Errorf("decrypt variable %s:%s", id, err)
+				return "", false
+			}
+			return plain, true
+		}
 		return val, has
 	}
 	return "", false
@@ -72,8 +88,22 @@ func (m *Variables) Unmarshal(buf []byte, typ reflect.Type) (interface{}, []stri
 }
 
 func NewVariables(data map[string][]byte) eosc.IVariable {
+	return newVariables(data)
+}
+
+// NewCheckVariables 用于admin校验配置，外部变量来源的值由各节点读取，admin只记录引用，不读取本节点的值
+func NewCheckVariables(data map[string][]byte) eosc.IVariable {
+	v := newVariables(data)
+	v.skipProvider = true
+	return v
+}
+
+func newVariables(data map[string][]byte) *Variables {
 	v := &Variables{data: make(map[string]map[string]string, len(data)), requireManager: require.NewRequireManager()}
 	for namespace, value := range data {
+		if _, has := GetProvider(namespace); has {
+			log.Errorf("variable namespace %s conflicts with the provider of the same name, the stored variables are ignored", namespace)
+		}
 		nvs := make(map[string]string)
 		err := json.Unmarshal(value, &nvs)
 		if err != nil {
@@ -84,6 +114,16 @@ func NewVariables(data map[string][]byte) eosc.IVariable {
 	return v
 }
 
+// isSkipped 变量来自外部变量来源且不读取值
+func (m *Variables) isSkipped(id string) bool {
+	if !m.skipProvider {
+		return false
+	}
+	namespace, _ := readId(id)
+	_, has := GetProvider(namespace)
+	return has
+}
+
 func (m *Variables) SetVariablesById(id string, variables []string) {
 	m.requireManager.Set(id, variables)
 }
@@ -107,7 +147,7 @@ func (m *Variables) check(namespace string, variables map[string]string) ([]stri
 		if v, ok := old[key]; ok {
 			if v != value {
 				// 将更新的key记录下来
-				affectIds = append(affectIds, m.requireManager.RequireBy(key)...)
+				affectIds = append(affectIds, m.requireManager.RequireBy(VariableId(key, namespace))...)
 			}
 			delete(old, key)
 			continue
@@ -115,13 +155,19 @@ func (m *Variables) check(namespace string, variables map[string]string) ([]stri
 	}
 	for key := range old {
 		// 删除的key
-		if m.requireManager.RequireByCount(key) > 0 {
-			return nil, fmt.Errorf("variable %s %w", key, ErrorVariableRequire)
+		id := VariableId(key, namespace)
+		if by := m.requireManager.RequireBy(id); len(by) > 0 {
+			return nil, fmt.Errorf("variable %s is used by %s:%w", id, strings.Join(by, ","), ErrorVariableRequire)
 		}
 	}
 
 	return affectIds, nil
 }
+
+// VariableId 返回变量的id，与配置中的引用 ${key@namespace} 一致
+func VariableId(key, namespace string) string {
+	return fmt.Sprintf("%s@%s", key, namespace)
+}
 func (m *Variables) Check(namespace string, variables map[string]string) ([]string, eosc.IVariable, error) {
 	// variables的key为：{变量名}@{namespace}，如：v1@default
 	m.lock.RLock()
@@ -135,12 +181,13 @@ func (m *Variables) Check(namespace string, variables map[string]string) ([]stri
 	return vs, clone, nil
 }
 
+// SetByNamespace 保存namespace的变量，variables 为空时删除namespace，是否允许修改由 Check 校验
 func (m *Variables) SetByNamespace(namespace string, variables map[string]string) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	_, err := m.check(namespace, variables)
-	if err != nil {
-		return err
+	if len(variables) == 0 {
+		delete(m.data, namespace)
+		return nil
 	}
 	m.data[namespace] = variables
 	return nil
@@ -161,6 +208,7 @@ func (m *Variables) clone() *Variables {
 		lock:           sync.RWMutex{},
 		data:           data,
 		requireManager: m.requireManager,
+		skipProvider:   m.skipProvider,
 	}
 }
 func (m *Variables) getByNamespace(namespace string) (map[string]string, bool) {
@@ -176,6 +224,17 @@ func (m *Variables) getByNamespace(namespace string) (map[string]string, bool) {
 	return newMap, true
 }
 
+// All 返回所有namespace的变量
+func (m *Variables) All() map[string]map[string]string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	rs := make(map[string]map[string]string, len(m.data))
+	for namespace := range m.data {
+		rs[namespace], _ = m.getByNamespace(namespace)
+	}
+	return rs
+}
+
 func (m *Variables) GetByNamespace(namespace string) (map[string]string, bool) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
@@ -0,0 +1,150 @@
+package variable
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eolinker/eosc/env"
+	"github.com/eolinker/eosc/log"
+)
+
+const (
+	// ProviderEnv 读取节点环境变量，如 ${HOSTNAME@env}
+	ProviderEnv = "env"
+	// ProviderFile 读取节点变量目录下的文件内容，文件名为变量名，如 ${db_password@file}
+	ProviderFile = "file"
+
+	envVariableDir      = "VARIABLE_DIR"
+	providerWatchPeriod = time.Second * 5
+)
+
+// IProvider 外部变量来源，对应namespace的变量不保存在集群中，由各节点在本地读取
+type IProvider interface {
+	Get(key string) (string, bool)
+}
+
+// IWatchProvider 可以监听变化的变量来源，变化时通过 handler 返回变化的变量名
+type IWatchProvider interface {
+	IProvider
+	Watch(ctx context.Context, handler func(keys []string))
+}
+
+var (
+	providerLock sync.RWMutex
+	providers    = map[string]IProvider{
+		ProviderEnv:  envProvider{},
+		ProviderFile: NewDirProvider(env.GetDefault(envVariableDir, filepath.Join(env.DataDir(), "variables"))),
+	}
+)
+
+// RegisterProvider 注册外部变量来源，namespace 相同时覆盖
+func RegisterProvider(namespace string, provider IProvider) {
+	providerLock.Lock()
+	defer providerLock.Unlock()
+	providers[namespace] = provider
+}
+
+// GetProvider 返回namespace对应的外部变量来源
+func GetProvider(namespace string) (IProvider, bool) {
+	providerLock.RLock()
+	defer providerLock.RUnlock()
+	p, has := providers[namespace]
+	return p, has
+}
+
+// Providers 返回所有外部变量来源
+func Providers() map[string]IProvider {
+	providerLock.RLock()
+	defer providerLock.RUnlock()
+	rs := make(map[string]IProvider, len(providers))
+	for namespace, p := range providers {
+		rs[namespace] = p
+	}
+	return rs
+}
+
+type envProvider struct {
+}
+
+func (envProvider) Get(key string) (string, bool) {
+	if env.IsAppEnv(key) {
+		// 程序自身的配置(如secret变量的密钥)不允许作为变量读取
+		return "", false
+	}
+	return os.LookupEnv(key)
+}
+
+// DirProvider 读取目录下的文件作为变量，适用于挂载的密钥文件，定时检查文件的修改时间
+type DirProvider struct {
+	dir string
+}
+
+func NewDirProvider(dir string) *DirProvider {
+	return &DirProvider{dir: env.FormatPath(dir)}
+}
+
+func (d *DirProvider) Get(key string) (string, bool) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", false
+	}
+	data, err := ioutil.ReadFile(filepath.Join(d.dir, key))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimRight(string(data), "\r\n"), true
+}
+
+func (d *DirProvider) Watch(ctx context.Context, handler func(keys []string)) {
+	last := d.scan()
+	ticker := time.NewTicker(providerWatchPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := d.scan()
+			changed := make([]string, 0)
+			for key, t := range current {
+				if old, has := last[key]; !has || !old.Equal(t) {
+					changed = append(changed, key)
+				}
+			}
+			for key := range last {
+				if _, has := current[key]; !has {
+					changed = append(changed, key)
+				}
+			}
+			last = current
+			if len(changed) > 0 {
+				log.Debug("variable files changed:", changed)
+				handler(changed)
+			}
+		}
+	}
+}
+
+// scan 返回目录下文件的修改时间，os.Stat 会跟随软链接，挂载的密钥更新时可以感知
+func (d *DirProvider) scan() map[string]time.Time {
+	rs := make(map[string]time.Time)
+	entries, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return rs
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(d.dir, e.Name()))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		rs[e.Name()] = info.ModTime()
+	}
+	return rs
+}
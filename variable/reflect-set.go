@@ -14,6 +14,7 @@ import (
 var (
 	ErrorVariableNotFound = errors.New("data not found")
 	ErrorUnsupportedKind  = errors.New("unsupported kind")
+	ErrorVariableSkipped  = errors.New("variable skipped")
 )
 
 func stringSet(value reflect.Value, targetVal reflect.Value, variables eosc.IVariable) ([]string, error) {
@@ -21,11 +22,24 @@ func stringSet(value reflect.Value, targetVal reflect.Value, variables eosc.IVar
 		return stringSet(value, targetVal.Elem(), variables)
 	}
 	builder := NewBuilder(value.String())
-	val, useVariables, success := builder.Replace(variables)
-	if !success {
-
-		return nil, ErrorVariableNotFound
+	values, useVariables, err := builder.ReplaceList(variables)
+	if errors.Is(err, ErrorVariableSkipped) {
+		// 值由各节点读取，这里保持零值
+		return useVariables, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if targetVal.Kind() == reflect.Slice && targetVal.Type().Elem().Kind() == reflect.String {
+		// ${list|split:,} 这样的表达式可以直接设置到字符串数组
+		list := reflect.MakeSlice(targetVal.Type(), len(values), len(values))
+		for i, v := range values {
+			list.Index(i).SetString(v)
+		}
+		targetVal.Set(list)
+		return useVariables, nil
 	}
+	val := strings.Join(values, listSeparator)
 	switch targetVal.Kind() {
 	case reflect.Int, reflect.Int32, reflect.Int64:
 		v, err := strconv.ParseInt(val, 10, 64)
@@ -0,0 +1,145 @@
+package variable
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/eolinker/eosc/env"
+)
+
+const (
+	// SecretPrefix 加密后的secret变量值前缀，保存和同步的都是密文
+	SecretPrefix = "{secret}"
+	// MaskedValue 接口返回secret变量时使用的掩码，保存时传入掩码表示保留原有的值
+	MaskedValue = "******"
+)
+
+var ErrorSecretCipher = errors.New("invalid secret")
+
+// ISecretCipher secret变量的加解密实现，默认使用节点持有的密钥，可以通过 SetSecretCipher 替换为KMS
+type ISecretCipher interface {
+	Encrypt(plain []byte) ([]byte, error)
+	Decrypt(data []byte) ([]byte, error)
+}
+
+var (
+	secretLock   sync.RWMutex
+	secretCipher ISecretCipher = new(nodeCipher)
+)
+
+// SetSecretCipher 设置secret变量的加解密实现
+func SetSecretCipher(c ISecretCipher) {
+	secretLock.Lock()
+	defer secretLock.Unlock()
+	secretCipher = c
+}
+
+func getSecretCipher() ISecretCipher {
+	secretLock.RLock()
+	defer secretLock.RUnlock()
+	return secretCipher
+}
+
+// IsSecret 判断变量值是否为secret变量
+func IsSecret(value string) bool {
+	return strings.HasPrefix(value, SecretPrefix)
+}
+
+// EncryptSecret 加密变量值，返回带 SecretPrefix 的密文
+func EncryptSecret(plain string) (string, error) {
+	data, err := getSecretCipher().Encrypt([]byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return SecretPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 生成的密文
+func DecryptSecret(value string) (string, error) {
+	if !IsSecret(value) {
+		return "", ErrorSecretCipher
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, SecretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("%w:%s", ErrorSecretCipher, err)
+	}
+	plain, err := getSecretCipher().Decrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// MaskSecrets 返回隐藏了secret变量值的副本
+func MaskSecrets(variables map[string]string) map[string]string {
+	rs := make(map[string]string, len(variables))
+	for k, v := range variables {
+		if IsSecret(v) {
+			v = MaskedValue
+		}
+		rs[k] = v
+	}
+	return rs
+}
+
+// nodeCipher 使用节点密钥的 AES-GCM 加密，密钥见 env.SecretKey
+type nodeCipher struct {
+	once sync.Once
+	aead cipher.AEAD
+	err  error
+}
+
+func (n *nodeCipher) init() {
+	key, err := env.SecretKey()
+	if err != nil {
+		n.err = fmt.Errorf("read secret key:%w", err)
+		return
+	}
+	if len(key) == 0 {
+		n.err = errors.New("secret key is empty")
+		return
+	}
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		n.err = err
+		return
+	}
+	n.aead, n.err = cipher.NewGCM(block)
+}
+
+func (n *nodeCipher) Encrypt(plain []byte) ([]byte, error) {
+	n.once.Do(n.init)
+	if n.err != nil {
+		return nil, n.err
+	}
+	nonce := make([]byte, n.aead.NonceSize(), n.aead.NonceSize()+len(plain)+n.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return n.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (n *nodeCipher) Decrypt(data []byte) ([]byte, error) {
+	n.once.Do(n.init)
+	if n.err != nil {
+		return nil, n.err
+	}
+	size := n.aead.NonceSize()
+	if len(data) < size {
+		return nil, ErrorSecretCipher
+	}
+	plain, err := n.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w:%s", ErrorSecretCipher, err)
+	}
+	return plain, nil
+}
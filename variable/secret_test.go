@@ -0,0 +1,35 @@
+package variable
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eolinker/eosc/env"
+)
+
+func TestSecretVariable(t *testing.T) {
+	env.SetEnv("SECRET_KEY", "test-secret-key")
+	SetSecretCipher(new(nodeCipher))
+
+	value, err := EncryptSecret("api-key-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSecret(value) || value == SecretPrefix+"api-key-value" {
+		t.Fatalf("value not encrypted:%s", value)
+	}
+	data, _ := json.Marshal(map[string]string{"key": value, "host": "127.0.0.1"})
+	vs := NewVariables(map[string][]byte{"default": data})
+
+	if v, has := vs.Get("key@default"); !has || v != "api-key-value" {
+		t.Errorf("get secret variable:%s,%v", v, has)
+	}
+	masked, _ := vs.GetByNamespace("default")
+	masked = MaskSecrets(masked)
+	if masked["key"] != MaskedValue || masked["host"] != "127.0.0.1" {
+		t.Errorf("mask secret variable:%v", masked)
+	}
+	if _, err := DecryptSecret(SecretPrefix + "bad"); err == nil {
+		t.Error("decrypt invalid secret should fail")
+	}
+}
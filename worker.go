@@ -21,11 +21,12 @@ type IWorkers interface {
 }
 
 type TWorker struct {
-	Id         string      `json:"id,omitempty" yaml:"id"`
-	Name       string      `json:"name,omitempty" yaml:"name"`
-	Driver     string      `json:"driver,omitempty" yaml:"driver"`
-	Profession string      `json:"profession,omitempty" yaml:"profession"`
-	Create     time.Time   `json:"create" yaml:"create"`
-	Update     time.Time   `json:"update" yaml:"update"`
-	Data       interface{} `json:"data,omitempty" yaml:"data"`
+	Id         string            `json:"id,omitempty" yaml:"id"`
+	Name       string            `json:"name,omitempty" yaml:"name"`
+	Driver     string            `json:"driver,omitempty" yaml:"driver"`
+	Profession string            `json:"profession,omitempty" yaml:"profession"`
+	Create     time.Time         `json:"create" yaml:"create"`
+	Update     time.Time         `json:"update" yaml:"update"`
+	Data       interface{}       `json:"data,omitempty" yaml:"data"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels"`
 }